@@ -0,0 +1,72 @@
+// Package chatmodel builds the chat model used to generate answers from
+// configuration: ARK, any OpenAI-compatible endpoint, or a local echo model.
+package chatmodel
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cloudwego/eino-ext/components/model/ark"
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+)
+
+// Supported providers
+const (
+	ProviderARK    = "ark"
+	ProviderOpenAI = "openai"
+	ProviderEcho   = "echo"
+	// ProviderNone disables generation; the server runs retrieval-only.
+	ProviderNone = "none"
+)
+
+type Config struct {
+	Provider string
+	APIKey   string
+	BaseURL  string
+	Model    string
+	// Template is the echo model's text/template, see EchoModel.
+	Template string
+}
+
+// New returns the chat model for config, or nil for ProviderNone.
+func New(ctx context.Context, config *Config) (model.BaseChatModel, error) {
+	switch config.Provider {
+	case ProviderARK:
+		if config.APIKey == "" {
+			return nil, fmt.Errorf("ARK provider requires an API key")
+		}
+		chatModel, err := ark.NewChatModel(ctx, &ark.ChatModelConfig{
+			APIKey:  config.APIKey,
+			BaseURL: config.BaseURL,
+			Model:   config.Model,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create ARK chat model: %w", err)
+		}
+		return chatModel, nil
+
+	case ProviderOpenAI:
+		if config.Model == "" {
+			return nil, fmt.Errorf("OpenAI provider requires a model")
+		}
+		chatModel, err := openai.NewChatModel(ctx, &openai.ChatModelConfig{
+			APIKey:  config.APIKey,
+			BaseURL: config.BaseURL,
+			Model:   config.Model,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OpenAI chat model: %w", err)
+		}
+		return chatModel, nil
+
+	case ProviderEcho:
+		return NewEchoModel(config.Template)
+
+	case ProviderNone:
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("unknown chat provider %q, expected ark, openai, echo or none", config.Provider)
+	}
+}
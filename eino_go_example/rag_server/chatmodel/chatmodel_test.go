@@ -0,0 +1,132 @@
+package chatmodel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino-ext/components/model/ark"
+	"github.com/cloudwego/eino-ext/components/model/openai"
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		check   func(chatModel model.BaseChatModel) bool
+		wantErr string
+	}{
+		{
+			name:   "ark",
+			config: Config{Provider: ProviderARK, APIKey: "key", BaseURL: "https://ark.example.com/api/v3", Model: "ep-1"},
+			check:  func(m model.BaseChatModel) bool { _, ok := m.(*ark.ChatModel); return ok },
+		},
+		{
+			name:    "ark without an API key",
+			config:  Config{Provider: ProviderARK, Model: "ep-1"},
+			wantErr: "requires an API key",
+		},
+		{
+			name:   "openai",
+			config: Config{Provider: ProviderOpenAI, APIKey: "key", BaseURL: "https://llm.example.com/v1", Model: "gpt-4o-mini"},
+			check:  func(m model.BaseChatModel) bool { _, ok := m.(*openai.ChatModel); return ok },
+		},
+		{
+			name:    "openai without a model",
+			config:  Config{Provider: ProviderOpenAI, APIKey: "key"},
+			wantErr: "requires a model",
+		},
+		{
+			name:   "echo",
+			config: Config{Provider: ProviderEcho},
+			check:  func(m model.BaseChatModel) bool { _, ok := m.(*EchoModel); return ok },
+		},
+		{
+			name:    "echo with an invalid template",
+			config:  Config{Provider: ProviderEcho, Template: "{{.Input"},
+			wantErr: "failed to parse echo template",
+		},
+		{
+			name:   "none",
+			config: Config{Provider: ProviderNone},
+			check:  func(m model.BaseChatModel) bool { return m == nil },
+		},
+		{
+			name:    "unknown",
+			config:  Config{Provider: "claude"},
+			wantErr: `unknown chat provider "claude"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chatModel, err := New(context.Background(), &tt.config)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("New = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(chatModel) {
+				t.Errorf("New = %T", chatModel)
+			}
+		})
+	}
+}
+
+// TestOpenAICompatible generates with the openai provider against a local
+// OpenAI-compatible endpoint.
+func TestOpenAICompatible(t *testing.T) {
+	var request struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "chatcmpl-1", "object": "chat.completion", "model": "local-model",
+			"choices": [{"index": 0, "message": {"role": "assistant", "content": "Hello from the local model"}, "finish_reason": "stop"}],
+			"usage": {"prompt_tokens": 5, "completion_tokens": 5, "total_tokens": 10}}`))
+	}))
+	defer server.Close()
+
+	chatModel, err := New(context.Background(), &Config{Provider: ProviderOpenAI, APIKey: "key", BaseURL: server.URL + "/v1", Model: "local-model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer, err := chatModel.Generate(context.Background(), []*schema.Message{
+		schema.SystemMessage("Be brief."),
+		schema.UserMessage("Hi"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Content != "Hello from the local model" {
+		t.Errorf("answer = %q", answer.Content)
+	}
+	if authorization != "Bearer key" {
+		t.Errorf("Authorization = %q, want the API key", authorization)
+	}
+	if request.Model != "local-model" || len(request.Messages) != 2 || request.Messages[1].Content != "Hi" {
+		t.Errorf("request = %+v", request)
+	}
+}
@@ -0,0 +1,116 @@
+package chatmodel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+)
+
+// DefaultEchoTemplate answers with the last user message unchanged.
+const DefaultEchoTemplate = "{{.Input}}"
+
+// EchoModel is a deterministic chat model for tests and demos. It renders its
+// template with EchoData and needs no network or credentials.
+type EchoModel struct {
+	tmpl *template.Template
+}
+
+// EchoData is the template input of an EchoModel.
+type EchoData struct {
+	// System is the first system message, if any.
+	System string
+	// Input is the last user message.
+	Input    string
+	Messages []*schema.Message
+}
+
+var _ model.BaseChatModel = (*EchoModel)(nil)
+
+func NewEchoModel(text string) (*EchoModel, error) {
+	if text == "" {
+		text = DefaultEchoTemplate
+	}
+	tmpl, err := template.New("echo").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse echo template: %w", err)
+	}
+	return &EchoModel{tmpl: tmpl}, nil
+}
+
+func (m *EchoModel) Generate(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.Message, error) {
+	content, err := m.render(input)
+	if err != nil {
+		return nil, err
+	}
+
+	message := schema.AssistantMessage(content, nil)
+	message.ResponseMeta = &schema.ResponseMeta{
+		FinishReason: "stop",
+		Usage:        usage(input, content),
+	}
+	return message, nil
+}
+
+// Stream emits the rendered answer word by word; the last chunk carries the
+// finish reason and usage.
+func (m *EchoModel) Stream(ctx context.Context, input []*schema.Message, opts ...model.Option) (*schema.StreamReader[*schema.Message], error) {
+	content, err := m.render(input)
+	if err != nil {
+		return nil, err
+	}
+
+	words := strings.SplitAfter(content, " ")
+	chunks := make([]*schema.Message, 0, len(words)+1)
+	for _, word := range words {
+		if word != "" {
+			chunks = append(chunks, schema.AssistantMessage(word, nil))
+		}
+	}
+	last := schema.AssistantMessage("", nil)
+	last.ResponseMeta = &schema.ResponseMeta{
+		FinishReason: "stop",
+		Usage:        usage(input, content),
+	}
+	chunks = append(chunks, last)
+
+	return schema.StreamReaderFromArray(chunks), nil
+}
+
+func (m *EchoModel) render(input []*schema.Message) (string, error) {
+	data := EchoData{Messages: input}
+	for _, msg := range input {
+		switch msg.Role {
+		case schema.System:
+			if data.System == "" {
+				data.System = msg.Content
+			}
+		case schema.User:
+			data.Input = msg.Content
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := m.tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render echo template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// usage counts words rather than tokens, which is enough for a fake.
+func usage(input []*schema.Message, content string) *schema.TokenUsage {
+	prompt := 0
+	for _, msg := range input {
+		prompt += len(strings.Fields(msg.Content))
+	}
+	completion := len(strings.Fields(content))
+	return &schema.TokenUsage{
+		PromptTokens:     prompt,
+		CompletionTokens: completion,
+		TotalTokens:      prompt + completion,
+	}
+}
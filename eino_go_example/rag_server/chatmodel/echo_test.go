@@ -0,0 +1,103 @@
+package chatmodel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func TestEchoModel(t *testing.T) {
+	input := []*schema.Message{
+		schema.SystemMessage("Answer from the context."),
+		schema.UserMessage("first question"),
+		schema.AssistantMessage("first answer", nil),
+		schema.UserMessage("second question"),
+	}
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{name: "default template", want: "second question"},
+		{name: "system prompt", template: "{{.System}} / {{.Input}}", want: "Answer from the context. / second question"},
+		{name: "messages", template: "{{len .Messages}} messages", want: "4 messages"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			echo, err := NewEchoModel(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			answer, err := echo.Generate(context.Background(), input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if answer.Role != schema.Assistant || answer.Content != tt.want {
+				t.Errorf("answer = %s %q, want assistant %q", answer.Role, answer.Content, tt.want)
+			}
+			if answer.ResponseMeta == nil || answer.ResponseMeta.FinishReason != "stop" {
+				t.Errorf("response meta = %+v", answer.ResponseMeta)
+			}
+		})
+	}
+}
+
+func TestEchoModelUsage(t *testing.T) {
+	echo, err := NewEchoModel("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer, err := echo.Generate(context.Background(), []*schema.Message{
+		schema.SystemMessage("Be brief."),
+		schema.UserMessage("three word question"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	usage := answer.ResponseMeta.Usage
+	if usage.PromptTokens != 5 || usage.CompletionTokens != 3 || usage.TotalTokens != 8 {
+		t.Errorf("usage = %+v, want words counted", usage)
+	}
+}
+
+func TestEchoModelStream(t *testing.T) {
+	echo, err := NewEchoModel("{{.Input}} again")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := echo.Stream(context.Background(), []*schema.Message{schema.UserMessage("say it")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	var chunks []*schema.Message
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want a word per chunk and a final one", len(chunks))
+	}
+	var content strings.Builder
+	for _, chunk := range chunks {
+		content.WriteString(chunk.Content)
+	}
+	if content.String() != "say it again" {
+		t.Errorf("streamed %q, want %q", content.String(), "say it again")
+	}
+	last := chunks[len(chunks)-1]
+	if last.Content != "" || last.ResponseMeta == nil || last.ResponseMeta.FinishReason != "stop" || last.ResponseMeta.Usage.CompletionTokens != 3 {
+		t.Errorf("last chunk = %+v, want the finish reason and usage", last)
+	}
+}
@@ -0,0 +1,181 @@
+// Package chunking splits parsed document sections into chunks for
+// ingestion, with a choice of strategies: recursive character splitting,
+// markdown headers, sentences or fixed token windows. Chunkers are eino
+// document transformers and keep each section's metadata.
+package chunking
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cloudwego/eino/components/document"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Chunking strategies
+const (
+	// StrategyRecursive splits at paragraphs, then lines, then sentences
+	// until chunks fit.
+	StrategyRecursive = "recursive"
+	// StrategyMarkdown splits at markdown headers first, recording them as
+	// the chunk's heading, then recursively.
+	StrategyMarkdown = "markdown"
+	// StrategySentence packs whole sentences into chunks.
+	StrategySentence = "sentence"
+	// StrategyToken cuts fixed windows of tokens.
+	StrategyToken = "token"
+)
+
+// Defaults, in characters, or tokens for StrategyToken
+const (
+	defaultChunkSize      = 800
+	defaultOverlap        = 100
+	defaultTokenChunkSize = 256
+	defaultTokenOverlap   = 32
+	maxChunkSize          = 100000
+)
+
+// ErrInvalidConfig is returned for chunking settings that cannot be used.
+var ErrInvalidConfig = errors.New("invalid chunking config")
+
+// Config selects how documents are chunked. ChunkSize and Overlap count
+// characters, or tokens for StrategyToken; zero values use the defaults.
+type Config struct {
+	Strategy  string `json:"strategy,omitempty"`
+	ChunkSize int    `json:"chunk_size,omitempty"`
+	// Overlap is the text repeated at the start of a chunk from the end of
+	// the previous one. Nil uses the default, which is not zero.
+	Overlap *int `json:"overlap,omitempty"`
+}
+
+// WithDefaults fills in the settings left unset.
+func (c Config) WithDefaults() Config {
+	if c.Strategy == "" {
+		c.Strategy = StrategyRecursive
+	}
+	size, overlap := defaultChunkSize, defaultOverlap
+	if c.Strategy == StrategyToken {
+		size, overlap = defaultTokenChunkSize, defaultTokenOverlap
+	}
+	if c.ChunkSize == 0 {
+		c.ChunkSize = size
+	}
+	if c.Overlap == nil {
+		overlap = min(overlap, c.ChunkSize/2)
+		c.Overlap = &overlap
+	}
+	return c
+}
+
+// Merge returns c with the settings that override sets replaced.
+func (c Config) Merge(override Config) Config {
+	if override.Strategy != "" {
+		// Sizes are in other units for tokens, so do not carry them over
+		if (override.Strategy == StrategyToken) != (c.Strategy == StrategyToken) {
+			c.ChunkSize, c.Overlap = 0, nil
+		}
+		c.Strategy = override.Strategy
+	}
+	if override.ChunkSize != 0 {
+		c.ChunkSize = override.ChunkSize
+	}
+	if override.Overlap != nil {
+		c.Overlap = override.Overlap
+	}
+	return c
+}
+
+// Validate checks the settings after defaults are applied.
+func (c Config) Validate() error {
+	c = c.WithDefaults()
+	switch c.Strategy {
+	case StrategyRecursive, StrategyMarkdown, StrategySentence, StrategyToken:
+	default:
+		return fmt.Errorf("%w: unknown strategy %q, expected recursive, markdown, sentence or token", ErrInvalidConfig, c.Strategy)
+	}
+	if c.ChunkSize < 1 || c.ChunkSize > maxChunkSize {
+		return fmt.Errorf("%w: chunk_size must be between 1 and %d", ErrInvalidConfig, maxChunkSize)
+	}
+	if *c.Overlap < 0 || *c.Overlap >= c.ChunkSize {
+		return fmt.Errorf("%w: overlap must be at least 0 and less than chunk_size", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// New returns the chunker for config.
+func New(ctx context.Context, config Config) (document.Transformer, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.WithDefaults()
+
+	var (
+		split document.Transformer
+		err   error
+	)
+	switch config.Strategy {
+	case StrategyRecursive:
+		split, err = newRecursive(ctx, config.ChunkSize, *config.Overlap)
+	case StrategyMarkdown:
+		split, err = newMarkdown(ctx, config.ChunkSize, *config.Overlap)
+	case StrategySentence:
+		split = &sentenceSplitter{size: config.ChunkSize, overlap: *config.Overlap}
+	case StrategyToken:
+		split = &tokenSplitter{size: config.ChunkSize, overlap: *config.Overlap}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &chunker{split: split}, nil
+}
+
+// LoadFile reads per-collection chunking settings from a JSON object mapping
+// collection names to configs.
+func LoadFile(path string) (map[string]Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunking config: %w", err)
+	}
+	var configs map[string]Config
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("failed to parse chunking config %s: %w", path, err)
+	}
+	for name, config := range configs {
+		if err := config.Validate(); err != nil {
+			return nil, fmt.Errorf("collection %s: %w", name, err)
+		}
+	}
+	return configs, nil
+}
+
+// chunker trims the chunks of a strategy and drops empty ones.
+type chunker struct {
+	split document.Transformer
+}
+
+func (c *chunker) Transform(ctx context.Context, docs []*schema.Document, opts ...document.TransformerOption) ([]*schema.Document, error) {
+	chunks, err := c.split.Transform(ctx, docs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	kept := chunks[:0]
+	for _, chunk := range chunks {
+		if chunk.Content = strings.TrimSpace(chunk.Content); chunk.Content != "" {
+			kept = append(kept, chunk)
+		}
+	}
+	return kept, nil
+}
+
+// copyMeta gives a chunk its own copy of its section's metadata.
+func copyMeta(meta map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,280 @@
+package chunking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+
+	"rag-backend/docparse"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestConfigMerge(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     Config
+		override Config
+		want     Config
+	}{
+		{
+			name:     "empty override",
+			base:     Config{Strategy: StrategyMarkdown, ChunkSize: 500, Overlap: intPtr(50)},
+			override: Config{},
+			want:     Config{Strategy: StrategyMarkdown, ChunkSize: 500, Overlap: intPtr(50)},
+		},
+		{
+			name:     "size only",
+			base:     Config{Strategy: StrategyMarkdown, ChunkSize: 500, Overlap: intPtr(50)},
+			override: Config{ChunkSize: 300},
+			want:     Config{Strategy: StrategyMarkdown, ChunkSize: 300, Overlap: intPtr(50)},
+		},
+		{
+			name:     "zero overlap",
+			base:     Config{ChunkSize: 500, Overlap: intPtr(50)},
+			override: Config{Overlap: intPtr(0)},
+			want:     Config{ChunkSize: 500, Overlap: intPtr(0)},
+		},
+		{
+			name:     "character sizes dropped for tokens",
+			base:     Config{Strategy: StrategyRecursive, ChunkSize: 500, Overlap: intPtr(50)},
+			override: Config{Strategy: StrategyToken},
+			want:     Config{Strategy: StrategyToken},
+		},
+		{
+			name:     "sizes kept between character strategies",
+			base:     Config{Strategy: StrategyRecursive, ChunkSize: 500},
+			override: Config{Strategy: StrategySentence},
+			want:     Config{Strategy: StrategySentence, ChunkSize: 500},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.base.Merge(tt.override); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %s, want %s", formatConfig(got), formatConfig(tt.want))
+			}
+		})
+	}
+}
+
+func TestConfigWithDefaults(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		want   Config
+	}{
+		{name: "empty", config: Config{}, want: Config{Strategy: StrategyRecursive, ChunkSize: 800, Overlap: intPtr(100)}},
+		{name: "token", config: Config{Strategy: StrategyToken}, want: Config{Strategy: StrategyToken, ChunkSize: 256, Overlap: intPtr(32)}},
+		{name: "small chunks", config: Config{ChunkSize: 100}, want: Config{Strategy: StrategyRecursive, ChunkSize: 100, Overlap: intPtr(50)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.WithDefaults(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WithDefaults() = %s, want %s", formatConfig(got), formatConfig(tt.want))
+			}
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "defaults", config: Config{}},
+		{name: "sentence", config: Config{Strategy: StrategySentence, ChunkSize: 1, Overlap: intPtr(0)}},
+		{name: "unknown strategy", config: Config{Strategy: "semantic"}, wantErr: true},
+		{name: "negative size", config: Config{ChunkSize: -1}, wantErr: true},
+		{name: "too large", config: Config{ChunkSize: maxChunkSize + 1}, wantErr: true},
+		{name: "negative overlap", config: Config{Overlap: intPtr(-1)}, wantErr: true},
+		{name: "overlap as large as chunk", config: Config{ChunkSize: 100, Overlap: intPtr(100)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Validate() = %v, want ErrInvalidConfig", err)
+			}
+		})
+	}
+}
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "   \n\t", want: 0},
+		{text: "go", want: 1},
+		{text: "goroutine", want: 3},
+		{text: "hello, world!", want: 6},
+		{text: "3.14", want: 3},
+		{text: "中文分词", want: 4},
+		{text: "eino 框架", want: 3},
+		{text: "café", want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := CountTokens(tt.text); got != tt.want {
+				t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkers(t *testing.T) {
+	meta := map[string]interface{}{docparse.MetaHeading: "Guide"}
+	tests := []struct {
+		name   string
+		config Config
+		input  string
+		want   []string
+		// wantHeadings are the chunks' MetaHeading, when the strategy sets it
+		wantHeadings []string
+	}{
+		{
+			name:   "recursive fits",
+			config: Config{ChunkSize: 100},
+			input:  "First paragraph.\n\nSecond paragraph.",
+			want:   []string{"First paragraph.\n\nSecond paragraph."},
+		},
+		{
+			name:   "recursive paragraphs",
+			config: Config{ChunkSize: 20, Overlap: intPtr(0)},
+			input:  "First paragraph.\n\nSecond paragraph.\n\nThird.",
+			want:   []string{"First paragraph.", "Second paragraph.", "Third."},
+		},
+		{
+			name:         "markdown headers",
+			config:       Config{Strategy: StrategyMarkdown, ChunkSize: 100, Overlap: intPtr(0)},
+			input:        "Intro.\n# Setup\nInstall it.\n## Linux\nUse apt.",
+			want:         []string{"Intro.", "# Setup\nInstall it.", "## Linux\nUse apt."},
+			wantHeadings: []string{"Guide", "Guide > Setup", "Guide > Setup > Linux"},
+		},
+		{
+			name:   "sentence packing",
+			config: Config{Strategy: StrategySentence, ChunkSize: 30, Overlap: intPtr(0)},
+			input:  "One two. Three four. Five six seven.\n\nEight.",
+			want:   []string{"One two. Three four.", "Five six seven. Eight."},
+		},
+		{
+			name:   "sentence overlap",
+			config: Config{Strategy: StrategySentence, ChunkSize: 22, Overlap: intPtr(10)},
+			input:  "Alpha beta. Gamma. Delta epsilon.",
+			want:   []string{"Alpha beta. Gamma.", "Gamma. Delta epsilon."},
+		},
+		{
+			name:   "sentence decimals and cjk",
+			config: Config{Strategy: StrategySentence, ChunkSize: 12, Overlap: intPtr(0)},
+			input:  "Pi is 3.14 ok. 你好。世界！",
+			want:   []string{"Pi is 3.14", "ok. 你好。 世界！"},
+		},
+		{
+			name:   "sentence cut mid-word",
+			config: Config{Strategy: StrategySentence, ChunkSize: 5, Overlap: intPtr(0)},
+			input:  "abcdefghij",
+			want:   []string{"abcde", "fghij"},
+		},
+		{
+			name:   "token windows",
+			config: Config{Strategy: StrategyToken, ChunkSize: 3, Overlap: intPtr(1)},
+			input:  "a b c d e f",
+			want:   []string{"a b c", "c d e", "e f"},
+		},
+		{
+			name:   "token window exact",
+			config: Config{Strategy: StrategyToken, ChunkSize: 3, Overlap: intPtr(0)},
+			input:  "a b c d e f",
+			want:   []string{"a b c", "d e f"},
+		},
+		{
+			name:   "empty",
+			config: Config{Strategy: StrategyToken},
+			input:  "  \n ",
+			want:   []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			chunker, err := New(ctx, tt.config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			section := &schema.Document{Content: tt.input, MetaData: copyMeta(meta)}
+			chunks, err := chunker.Transform(ctx, []*schema.Document{section})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, len(chunks))
+			for i, chunk := range chunks {
+				got[i] = chunk.Content
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("chunks = %q, want %q", got, tt.want)
+			}
+			for i, chunk := range chunks {
+				want := "Guide"
+				if tt.wantHeadings != nil {
+					want = tt.wantHeadings[i]
+				}
+				if chunk.MetaData[docparse.MetaHeading] != want {
+					t.Errorf("chunk %d heading = %v, want %q", i, chunk.MetaData[docparse.MetaHeading], want)
+				}
+			}
+		})
+	}
+}
+
+// TestChunkMetadataCopied checks that chunks of one section do not share a
+// metadata map, so backends can set per-chunk fields.
+func TestChunkMetadataCopied(t *testing.T) {
+	for _, strategy := range []string{StrategyRecursive, StrategyMarkdown, StrategySentence, StrategyToken} {
+		t.Run(strategy, func(t *testing.T) {
+			ctx := context.Background()
+			chunker, err := New(ctx, Config{Strategy: strategy, ChunkSize: 10, Overlap: intPtr(0)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			section := &schema.Document{
+				Content:  strings.Repeat("Some words here. ", 5),
+				MetaData: map[string]interface{}{docparse.MetaPage: 1},
+			}
+			chunks, err := chunker.Transform(ctx, []*schema.Document{section})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(chunks) < 2 {
+				t.Fatalf("got %d chunks, want several", len(chunks))
+			}
+			chunks[0].MetaData["chunk"] = 0
+			for i, chunk := range chunks[1:] {
+				if _, ok := chunk.MetaData["chunk"]; ok {
+					t.Errorf("chunk %d shares metadata with chunk 0", i+1)
+				}
+				if chunk.MetaData[docparse.MetaPage] != 1 {
+					t.Errorf("chunk %d metadata = %v, want the section's page", i+1, chunk.MetaData)
+				}
+			}
+		})
+	}
+}
+
+func formatConfig(c Config) string {
+	overlap := "nil"
+	if c.Overlap != nil {
+		overlap = fmt.Sprint(*c.Overlap)
+	}
+	return fmt.Sprintf("{%s %d %s}", c.Strategy, c.ChunkSize, overlap)
+}
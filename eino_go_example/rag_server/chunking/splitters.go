@@ -0,0 +1,198 @@
+package chunking
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino-ext/components/document/transformer/splitter/markdown"
+	"github.com/cloudwego/eino-ext/components/document/transformer/splitter/recursive"
+	"github.com/cloudwego/eino/components/document"
+	"github.com/cloudwego/eino/schema"
+
+	"rag-backend/docparse"
+)
+
+// separators are tried in order by the recursive splitter.
+var separators = []string{"\n\n", "\n", "。", ". ", "！", "! ", "？", "? ", "；", "; "}
+
+func newRecursive(ctx context.Context, size, overlap int) (document.Transformer, error) {
+	return recursive.NewSplitter(ctx, &recursive.Config{
+		ChunkSize:   size,
+		OverlapSize: overlap,
+		Separators:  separators,
+		LenFunc:     utf8.RuneCountInString,
+		KeepType:    recursive.KeepTypeEnd,
+	})
+}
+
+// headerKeys name the metadata the markdown header splitter records each
+// header level under.
+var headerKeys = []string{"_h1", "_h2", "_h3", "_h4", "_h5", "_h6"}
+
+// markdownSplitter splits at markdown headers and then recursively, so
+// sections longer than the chunk size still fit.
+type markdownSplitter struct {
+	headers   document.Transformer
+	recursive document.Transformer
+}
+
+func newMarkdown(ctx context.Context, size, overlap int) (document.Transformer, error) {
+	levels := make(map[string]string, len(headerKeys))
+	for i, key := range headerKeys {
+		levels[strings.Repeat("#", i+1)] = key
+	}
+	headers, err := markdown.NewHeaderSplitter(ctx, &markdown.HeaderConfig{Headers: levels})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create markdown splitter: %w", err)
+	}
+	split, err := newRecursive(ctx, size, overlap)
+	if err != nil {
+		return nil, err
+	}
+	return &markdownSplitter{headers: headers, recursive: split}, nil
+}
+
+func (m *markdownSplitter) Transform(ctx context.Context, docs []*schema.Document, opts ...document.TransformerOption) ([]*schema.Document, error) {
+	sections, err := m.headers.Transform(ctx, docs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	// Append the headers found to the heading the section already had
+	for _, section := range sections {
+		var path []string
+		if heading, ok := section.MetaData[docparse.MetaHeading].(string); ok && heading != "" {
+			path = append(path, heading)
+		}
+		for _, key := range headerKeys {
+			if header, ok := section.MetaData[key].(string); ok && header != "" {
+				path = append(path, header)
+			}
+			delete(section.MetaData, key)
+		}
+		if len(path) > 0 {
+			section.MetaData[docparse.MetaHeading] = strings.Join(path, " > ")
+		}
+	}
+	return m.recursive.Transform(ctx, sections, opts...)
+}
+
+// sentenceSplitter packs whole sentences into chunks of up to size
+// characters, repeating trailing sentences of up to overlap characters.
+// Sentences longer than a chunk are cut.
+type sentenceSplitter struct {
+	size, overlap int
+}
+
+func (s *sentenceSplitter) Transform(ctx context.Context, docs []*schema.Document, opts ...document.TransformerOption) ([]*schema.Document, error) {
+	var chunks []*schema.Document
+	for _, doc := range docs {
+		var (
+			current []string
+			length  int
+		)
+		emit := func() {
+			if len(current) > 0 {
+				chunks = append(chunks, &schema.Document{ID: doc.ID, Content: strings.Join(current, " "), MetaData: copyMeta(doc.MetaData)})
+			}
+		}
+
+		for _, sentence := range s.sentences(doc.Content) {
+			n := utf8.RuneCountInString(sentence)
+			if length > 0 && length+1+n > s.size {
+				emit()
+				// Carry the trailing sentences that fit in the overlap
+				carried, carriedLength := 0, 0
+				for i := len(current) - 1; i >= 0; i-- {
+					l := utf8.RuneCountInString(current[i]) + 1
+					if carriedLength+l > s.overlap || carriedLength+l+n > s.size {
+						break
+					}
+					carried++
+					carriedLength += l
+				}
+				current = append([]string(nil), current[len(current)-carried:]...)
+				length = max(carriedLength-1, 0)
+			}
+			if length > 0 {
+				length++
+			}
+			current = append(current, sentence)
+			length += n
+		}
+		emit()
+	}
+	return chunks, nil
+}
+
+// sentences splits text after sentence-ending punctuation and at blank
+// lines, cutting sentences longer than the chunk size at spaces.
+func (s *sentenceSplitter) sentences(text string) []string {
+	var sentences []string
+	add := func(sentence string) {
+		sentence = strings.Join(strings.Fields(sentence), " ")
+		for utf8.RuneCountInString(sentence) > s.size {
+			// Cut at the last space that fits, or mid-word without one
+			runes := []rune(sentence)
+			cut := s.size
+			if space := strings.LastIndex(string(runes[:s.size+1]), " "); space > 0 {
+				cut = utf8.RuneCountInString(sentence[:space])
+			}
+			sentences = append(sentences, string(runes[:cut]))
+			sentence = strings.TrimSpace(string(runes[cut:]))
+		}
+		if sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+	}
+
+	start := 0
+	for i, r := range text {
+		next := i + utf8.RuneLen(r)
+		end := false
+		switch r {
+		case '。', '！', '？', '；':
+			end = true
+		case '.', '!', '?', ';':
+			// Only before whitespace, so "3.14" and "e.g." inside words stay whole
+			following, _ := utf8.DecodeRuneInString(text[next:])
+			end = next == len(text) || unicode.IsSpace(following)
+		case '\n':
+			following, _ := utf8.DecodeRuneInString(text[next:])
+			end = following == '\n'
+		}
+		if end {
+			add(text[start:next])
+			start = next
+		}
+	}
+	add(text[start:])
+	return sentences
+}
+
+// tokenSplitter cuts windows of size tokens that start every size-overlap
+// tokens, as counted by CountTokens.
+type tokenSplitter struct {
+	size, overlap int
+}
+
+func (t *tokenSplitter) Transform(ctx context.Context, docs []*schema.Document, opts ...document.TransformerOption) ([]*schema.Document, error) {
+	var chunks []*schema.Document
+	for _, doc := range docs {
+		spans := tokenize(doc.Content)
+		for start := 0; start < len(spans); start += t.size - t.overlap {
+			end := min(start+t.size, len(spans))
+			chunks = append(chunks, &schema.Document{
+				ID:       doc.ID,
+				Content:  doc.Content[spans[start].start:spans[end-1].end],
+				MetaData: copyMeta(doc.MetaData),
+			})
+			if end == len(spans) {
+				break
+			}
+		}
+	}
+	return chunks, nil
+}
@@ -0,0 +1,62 @@
+package chunking
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// runesPerToken approximates how many letters of a word a subword tokenizer
+// such as bge-m3's folds into one token.
+const runesPerToken = 4
+
+// span is a token's byte range in the text it came from.
+type span struct {
+	start, end int
+}
+
+// CountTokens estimates how many tokens a multilingual subword tokenizer
+// makes of s: one per CJK character or punctuation mark and one per four
+// letters or digits of a word. It is a pure-Go estimate, not a tokenizer.
+func CountTokens(s string) int {
+	return len(tokenize(s))
+}
+
+func tokenize(s string) []span {
+	var spans []span
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case isWordRune(r):
+			// Cut the word into pieces of runesPerToken runes
+			start, runes := i, 0
+			for i < len(s) {
+				r, size := utf8.DecodeRuneInString(s[i:])
+				if !isWordRune(r) {
+					break
+				}
+				if runes == runesPerToken {
+					spans = append(spans, span{start, i})
+					start, runes = i, 0
+				}
+				i += size
+				runes++
+			}
+			spans = append(spans, span{start, i})
+		default:
+			spans = append(spans, span{i, i + size})
+			i += size
+		}
+	}
+	return spans
+}
+
+// isWordRune reports whether r is part of a space-separated word. CJK
+// characters are tokens of their own.
+func isWordRune(r rune) bool {
+	if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+		return false
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
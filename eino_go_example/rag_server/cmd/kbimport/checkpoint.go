@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Checkpoint entry states
+const (
+	statusImported = "imported"
+	statusQueued   = "queued"
+	statusFailed   = "failed"
+)
+
+// checkpointEntry records the last import of a document. Hash is the
+// SHA-256 of the content uploaded; JobID is set while its ingestion job runs.
+// Replaces lists the previously imported versions, deleted once this one is
+// imported.
+type checkpointEntry struct {
+	Hash      string    `json:"hash"`
+	DocID     string    `json:"doc_id,omitempty"`
+	JobID     string    `json:"job_id,omitempty"`
+	Replaces  []string  `json:"replaces,omitempty"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// checkpoint is the import state of each document by key, a path relative
+// to the imported directory or a URL. It is saved atomically.
+type checkpoint struct {
+	path string
+
+	mu      sync.Mutex
+	entries map[string]*checkpointEntry
+	dirty   bool
+}
+
+type checkpointFile struct {
+	Entries map[string]*checkpointEntry `json:"entries"`
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	c := &checkpoint{path: path, entries: make(map[string]*checkpointEntry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var file checkpointFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+	if file.Entries != nil {
+		c.entries = file.Entries
+	}
+	return c, nil
+}
+
+func (c *checkpoint) get(key string) (checkpointEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return checkpointEntry{}, false
+	}
+	return *entry, true
+}
+
+func (c *checkpoint) set(key string, entry checkpointEntry) {
+	entry.UpdatedAt = time.Now().UTC()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = &entry
+	c.dirty = true
+}
+
+func (c *checkpoint) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// save writes the checkpoint if it changed, replacing the file only once
+// the new one is complete.
+func (c *checkpoint) save() error {
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(checkpointFile{Entries: c.entries}, "", "  ")
+	c.dirty = false
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := c.write(data); err != nil {
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (c *checkpoint) write(data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"rag-backend/jobs"
+	"rag-backend/rag"
+)
+
+// Polling interval bounds while waiting for an ingestion job
+const (
+	minPollInterval = 500 * time.Millisecond
+	maxPollInterval = 5 * time.Second
+)
+
+var (
+	errJobNotFound = errors.New("job not found")
+	// errJobTimeout is returned when a job is still running after the job
+	// timeout; a later run waits for it again.
+	errJobTimeout = errors.New("job still running")
+)
+
+// client uploads documents to the RAG server's document and job endpoints.
+type client struct {
+	baseURL    string
+	http       *http.Client
+	jobTimeout time.Duration
+}
+
+func newClient(baseURL string, jobTimeout time.Duration) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		// Servers without the job queue ingest during the upload request
+		http:       &http.Client{Timeout: 10 * time.Minute},
+		jobTimeout: jobTimeout,
+	}
+}
+
+// health checks that the server is up before anything is uploaded.
+func (c *client) health(ctx context.Context) error {
+	var health struct {
+		Backend string `json:"backend"`
+	}
+	if _, err := c.do(ctx, http.MethodGet, "/health", "", nil, &health); err != nil {
+		return fmt.Errorf("server %s is not reachable: %w", c.baseURL, err)
+	}
+	return nil
+}
+
+// upload posts a document as a multipart file, as POST /documents accepts.
+func (c *client) upload(ctx context.Context, it *item, docID string, content []byte, contentType, metadata string) (*rag.UploadResponse, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, path.Base(it.name)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(content); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{"doc_id": docID, "doc_name": it.name, "metadata": metadata}
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := form.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	var response rag.UploadResponse
+	if _, err := c.do(ctx, http.MethodPost, "/documents", form.FormDataContentType(), &body, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// deleteDocument removes a document imported before. Documents that are
+// gone, or backends that cannot delete, are not an error.
+func (c *client) deleteDocument(ctx context.Context, docID string) error {
+	status, err := c.do(ctx, http.MethodDelete, "/documents/"+url.PathEscape(docID), "", nil, nil)
+	if status == http.StatusNotFound || status == http.StatusNotImplemented {
+		return nil
+	}
+	return err
+}
+
+// waitJob polls an ingestion job until it finishes, returning an error
+// unless it completes.
+func (c *client) waitJob(ctx context.Context, jobID string) (*jobs.Job, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.jobTimeout)
+	defer cancel()
+
+	interval := minPollInterval
+	for {
+		var job jobs.Job
+		status, err := c.do(ctx, http.MethodGet, "/jobs/"+url.PathEscape(jobID), "", nil, &job)
+		if status == http.StatusNotFound {
+			return nil, fmt.Errorf("%w: %s", errJobNotFound, jobID)
+		}
+		if err != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w after %s: %s", errJobTimeout, c.jobTimeout, jobID)
+			}
+			return nil, err
+		}
+		switch job.State {
+		case jobs.StateCompleted:
+			return &job, nil
+		case jobs.StateFailed, jobs.StateCanceled:
+			return nil, fmt.Errorf("job %s %s: %s", jobID, job.State, job.Error)
+		}
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("%w after %s: %s is %s", errJobTimeout, c.jobTimeout, jobID, job.State)
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval = min(interval*2, maxPollInterval)
+	}
+}
+
+// do sends a request and decodes a 2xx JSON response into out. Other
+// responses are errors with the server's message; the status is returned
+// whenever there was a response.
+func (c *client) do(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			if apiErr.Message != "" {
+				return resp.StatusCode, fmt.Errorf("%s %s: %s: %s", method, path, apiErr.Error, apiErr.Message)
+			}
+			return resp.StatusCode, fmt.Errorf("%s %s: %s", method, path, apiErr.Error)
+		}
+		return resp.StatusCode, fmt.Errorf("%s %s returned status %d", method, path, resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("failed to decode response of %s %s: %w", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+type importer struct {
+	opts       *options
+	client     *client
+	checkpoint *checkpoint
+	progress   *progress
+}
+
+// importItem imports one document and records the outcome.
+func (im *importer) importItem(ctx context.Context, it *item) {
+	if ctx.Err() != nil {
+		return
+	}
+	skipped, err := im.importDocument(ctx, it)
+	switch {
+	case ctx.Err() != nil:
+		// Interrupted: the checkpoint keeps any queued job to resume
+		return
+	case err != nil:
+		im.progress.logf("Failed to import %s: %v", it.key, err)
+		im.progress.add(outcomeFailed)
+	case skipped:
+		im.progress.add(outcomeSkipped)
+	default:
+		im.progress.add(outcomeImported)
+	}
+}
+
+// importDocument uploads a document unless the checkpoint shows the same
+// content was imported, and waits for its ingestion job. A changed document
+// is uploaded under a new doc ID, and the version imported before is deleted
+// only once the new one is in.
+func (im *importer) importDocument(ctx context.Context, it *item) (bool, error) {
+	content, contentType, err := it.load(ctx)
+	if err != nil {
+		return false, err
+	}
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+	docID := it.docID(hash)
+
+	prev, seen := im.checkpoint.get(it.key)
+	if seen && prev.Hash == hash {
+		switch {
+		case prev.Status == statusImported:
+			// Retry deleting what an earlier run failed to
+			return true, im.deleteReplaced(ctx, it, prev)
+		case prev.Status == statusQueued && prev.JobID != "":
+			// Queued by an interrupted run: wait for that job instead
+			err := im.wait(ctx, it, prev)
+			if !errors.Is(err, errJobNotFound) {
+				return false, err
+			}
+		}
+	}
+
+	replaces := slices.Clone(prev.Replaces)
+	if prev.DocID != "" {
+		if prev.Status == statusImported {
+			replaces = append(replaces, prev.DocID)
+		} else if err := im.client.deleteDocument(ctx, prev.DocID); err != nil {
+			// Whatever part of an unfinished attempt was written
+			return false, fmt.Errorf("failed to delete unfinished import: %w", err)
+		}
+	}
+	if i := slices.Index(replaces, docID); i >= 0 {
+		// Changed back to a version that is still imported
+		entry := checkpointEntry{Hash: hash, DocID: docID, Status: statusImported, Replaces: slices.Delete(replaces, i, i+1)}
+		im.checkpoint.set(it.key, entry)
+		return true, im.deleteReplaced(ctx, it, entry)
+	}
+
+	entry := checkpointEntry{Hash: hash, DocID: docID, Replaces: replaces}
+	response, err := im.client.upload(ctx, it, docID, content, contentType, im.opts.metadata)
+	if err != nil {
+		im.fail(it, entry, err)
+		return false, err
+	}
+	if response.JobID == "" {
+		// Ingested during the request
+		if response.DocumentID != "" {
+			entry.DocID = response.DocumentID
+		}
+		entry.Status = statusImported
+		im.checkpoint.set(it.key, entry)
+		return false, im.deleteReplaced(ctx, it, entry)
+	}
+
+	entry.JobID, entry.Status = response.JobID, statusQueued
+	im.checkpoint.set(it.key, entry)
+	return false, im.wait(ctx, it, entry)
+}
+
+// wait waits for the queued ingestion job of entry and records its outcome.
+func (im *importer) wait(ctx context.Context, it *item, entry checkpointEntry) error {
+	job, err := im.client.waitJob(ctx, entry.JobID)
+	if err != nil {
+		if ctx.Err() == nil && !errors.Is(err, errJobNotFound) && !errors.Is(err, errJobTimeout) {
+			im.fail(it, entry, err)
+		}
+		return err
+	}
+	if len(job.DocIDs) > 0 {
+		entry.DocID = job.DocIDs[0]
+	}
+	entry.JobID, entry.Status = "", statusImported
+	im.checkpoint.set(it.key, entry)
+	return im.deleteReplaced(ctx, it, entry)
+}
+
+// deleteReplaced deletes the versions an imported entry replaces, keeping
+// those that could not be deleted for the next run to retry.
+func (im *importer) deleteReplaced(ctx context.Context, it *item, entry checkpointEntry) error {
+	if len(entry.Replaces) == 0 {
+		return nil
+	}
+	var (
+		kept []string
+		errs []error
+	)
+	for _, docID := range entry.Replaces {
+		if err := im.client.deleteDocument(ctx, docID); err != nil {
+			kept = append(kept, docID)
+			errs = append(errs, fmt.Errorf("failed to delete previous version %s: %w", docID, err))
+		}
+	}
+	entry.Replaces = kept
+	im.checkpoint.set(it.key, entry)
+	return errors.Join(errs...)
+}
+
+// fail records a failed import, keeping the document ID so that a retry
+// first deletes whatever part of it was written, and the versions it was to
+// replace, which are still imported.
+func (im *importer) fail(it *item, entry checkpointEntry, err error) {
+	entry.JobID, entry.Status, entry.Error = "", statusFailed, err.Error()
+	im.checkpoint.set(it.key, entry)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer stores uploaded documents by doc_id and ingests them during the
+// request, as a server without the job queue does.
+type fakeServer struct {
+	mu         sync.Mutex
+	docs       map[string]string
+	failUpload bool
+	failDelete bool
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/documents":
+		if f.failUpload {
+			http.Error(w, `{"error": "upload failed"}`, http.StatusBadGateway)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, `{"error": "file is required"}`, http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		docID := r.FormValue("doc_id")
+		f.docs[docID] = string(content)
+		w.Write([]byte(`{"document_id": "` + docID + `", "status": "completed"}`))
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/documents/"):
+		docID := strings.TrimPrefix(r.URL.Path, "/documents/")
+		if f.failDelete {
+			http.Error(w, `{"error": "delete failed"}`, http.StatusBadGateway)
+			return
+		}
+		if _, ok := f.docs[docID]; !ok {
+			http.Error(w, `{"error": "not found"}`, http.StatusNotFound)
+			return
+		}
+		delete(f.docs, docID)
+		w.Write([]byte(`{"count": 1}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// contents returns the content of every stored document, sorted.
+func (f *fakeServer) contents() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	contents := make([]string, 0, len(f.docs))
+	for _, content := range f.docs {
+		contents = append(contents, content)
+	}
+	slices.Sort(contents)
+	return contents
+}
+
+func TestImportReplacesDocument(t *testing.T) {
+	fake := &fakeServer{docs: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "guide.md")
+	it := &item{key: "guide.md", path: path, name: "guide.md"}
+	im := &importer{
+		opts:       &options{},
+		client:     newClient(server.URL, time.Minute),
+		checkpoint: &checkpoint{path: filepath.Join(dir, "checkpoint.json"), entries: map[string]*checkpointEntry{}},
+	}
+
+	steps := []struct {
+		name        string
+		content     string
+		failUpload  bool
+		failDelete  bool
+		wantErr     bool
+		wantSkipped bool
+		// wantDocs are the contents the server holds afterwards
+		wantDocs     []string
+		wantReplaces int
+	}{
+		{name: "first import", content: "v1", wantDocs: []string{"v1"}},
+		{name: "unchanged", content: "v1", wantSkipped: true, wantDocs: []string{"v1"}},
+		{name: "failed upload keeps the previous version", content: "v2", failUpload: true, wantErr: true, wantDocs: []string{"v1"}, wantReplaces: 1},
+		{name: "retry replaces it", content: "v2", wantDocs: []string{"v2"}},
+		{name: "failed delete keeps both", content: "v3", failDelete: true, wantErr: true, wantDocs: []string{"v2", "v3"}, wantReplaces: 1},
+		{name: "next run deletes the previous version", content: "v3", wantSkipped: true, wantDocs: []string{"v3"}},
+		{name: "another change", content: "v4", failDelete: true, wantErr: true, wantDocs: []string{"v3", "v4"}, wantReplaces: 1},
+		{name: "changed back to a version still imported", content: "v3", wantSkipped: true, wantDocs: []string{"v3"}},
+	}
+	for _, step := range steps {
+		if err := os.WriteFile(path, []byte(step.content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		fake.mu.Lock()
+		fake.failUpload, fake.failDelete = step.failUpload, step.failDelete
+		fake.mu.Unlock()
+
+		skipped, err := im.importDocument(context.Background(), it)
+		if (err != nil) != step.wantErr {
+			t.Fatalf("%s: err = %v, want error %v", step.name, err, step.wantErr)
+		}
+		if skipped != step.wantSkipped {
+			t.Errorf("%s: skipped = %v, want %v", step.name, skipped, step.wantSkipped)
+		}
+		if got := fake.contents(); !slices.Equal(got, step.wantDocs) {
+			t.Errorf("%s: server holds %v, want %v", step.name, got, step.wantDocs)
+		}
+		entry, _ := im.checkpoint.get(it.key)
+		if len(entry.Replaces) != step.wantReplaces {
+			t.Errorf("%s: checkpoint replaces %v, want %d versions", step.name, entry.Replaces, step.wantReplaces)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Command kbimport bulk-loads documents into a running RAG server. It walks
+// a directory, or reads a sitemap or URL list, and uploads each document
+// through POST /documents, so documents are parsed, chunked and ingested as
+// any upload is. Unchanged documents are skipped by content hash, and a
+// checkpoint file lets an interrupted import resume where it stopped.
+//
+// Usage:
+//
+//	kbimport -dir ./handbook
+//	kbimport -urls sitemap.xml -concurrency 8
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type options struct {
+	server      string
+	dir         string
+	urls        string
+	extensions  map[string]bool
+	metadata    string
+	checkpoint  string
+	concurrency int
+	jobTimeout  time.Duration
+	dryRun      bool
+}
+
+func main() {
+	var (
+		opts       options
+		extensions string
+	)
+	flag.StringVar(&opts.server, "server", getEnvOrDefault("KBIMPORT_SERVER", "http://localhost:8080"), "base URL of the RAG server")
+	flag.StringVar(&opts.dir, "dir", "", "directory to import, recursively")
+	flag.StringVar(&opts.urls, "urls", "", "sitemap or URL list to import, a file or an http(s) URL")
+	flag.StringVar(&extensions, "ext", ".pdf,.docx,.md,.markdown,.html,.htm,.csv,.txt", "comma separated file extensions to import from -dir")
+	flag.StringVar(&opts.metadata, "metadata", "", "JSON object of metadata to set on every document")
+	flag.StringVar(&opts.checkpoint, "checkpoint", ".kbimport-checkpoint.json", "checkpoint file recording imported documents")
+	flag.IntVar(&opts.concurrency, "concurrency", 4, "documents imported at once")
+	flag.DurationVar(&opts.jobTimeout, "job-timeout", 30*time.Minute, "how long to wait for a queued document to be ingested")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "list the documents that would be imported without uploading")
+	flag.Parse()
+
+	if (opts.dir == "") == (opts.urls == "") {
+		fmt.Fprintln(os.Stderr, "exactly one of -dir or -urls is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if opts.concurrency < 1 {
+		log.Fatal("-concurrency must be at least 1")
+	}
+	if opts.metadata != "" {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(opts.metadata), &metadata); err != nil {
+			log.Fatal("-metadata must be a JSON object: ", err)
+		}
+	}
+	opts.extensions = make(map[string]bool)
+	for _, ext := range strings.Split(extensions, ",") {
+		if ext = strings.ToLower(strings.TrimSpace(ext)); ext != "" {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			opts.extensions[ext] = true
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stats, err := run(ctx, &opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if stats.failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// stats counts the outcome of each document.
+type stats struct {
+	imported, skipped, failed int
+}
+
+func run(ctx context.Context, opts *options) (*stats, error) {
+	var (
+		items []*item
+		err   error
+	)
+	if opts.dir != "" {
+		items, err = walkDir(opts.dir, opts.extensions)
+	} else {
+		items, err = readURLList(ctx, opts.urls)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint, err := loadCheckpoint(opts.checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if opts.dryRun {
+		// The doc_id of a new version depends on its content, so list the
+		// one last imported
+		for _, it := range items {
+			docID := "-"
+			if entry, ok := checkpoint.get(it.key); ok && entry.Status == statusImported {
+				docID = entry.DocID
+			}
+			fmt.Printf("%s\t%s\n", docID, it.key)
+		}
+		log.Printf("%d documents found, %d in the checkpoint", len(items), checkpoint.len())
+		return &stats{}, nil
+	}
+
+	client := newClient(opts.server, opts.jobTimeout)
+	if err := client.health(ctx); err != nil {
+		return nil, err
+	}
+
+	progress := newProgress(len(items))
+	importer := &importer{opts: opts, client: client, checkpoint: checkpoint, progress: progress}
+
+	// Save the checkpoint now and then, so a crash loses little work
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(5 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := checkpoint.save(); err != nil {
+					progress.logf("Failed to save checkpoint: %v", err)
+				}
+			}
+		}
+	}()
+
+	queue := make(chan *item)
+	var wg sync.WaitGroup
+	for i := 0; i < opts.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for it := range queue {
+				importer.importItem(ctx, it)
+			}
+		}()
+	}
+feed:
+	for _, it := range items {
+		select {
+		case <-ctx.Done():
+			break feed
+		case queue <- it:
+		}
+	}
+	close(queue)
+	wg.Wait()
+	close(done)
+	progress.finish()
+
+	if err := checkpoint.save(); err != nil {
+		return nil, err
+	}
+	s := progress.stats()
+	log.Printf("Imported %d, skipped %d unchanged, %d failed; checkpoint %s", s.imported, s.skipped, s.failed, opts.checkpoint)
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("interrupted; run again to resume")
+	}
+	return &s, nil
+}
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
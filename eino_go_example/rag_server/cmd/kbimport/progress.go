@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Outcomes of importing a document
+const (
+	outcomeImported = iota
+	outcomeSkipped
+	outcomeFailed
+)
+
+const (
+	barWidth = 30
+	// Redraw a terminal bar at most this often; log a line this often
+	// when stderr is not a terminal
+	redrawInterval = 100 * time.Millisecond
+	logInterval    = 10 * time.Second
+)
+
+// progress draws a progress bar on stderr, or logs progress lines when
+// stderr is not a terminal.
+type progress struct {
+	total    int
+	start    time.Time
+	terminal bool
+
+	mu        sync.Mutex
+	counts    stats
+	lastDraw  time.Time
+	drawnDone int
+}
+
+func newProgress(total int) *progress {
+	p := &progress{total: total, start: time.Now()}
+	if info, err := os.Stderr.Stat(); err == nil {
+		p.terminal = info.Mode()&os.ModeCharDevice != 0
+	}
+	p.mu.Lock()
+	p.draw(true)
+	p.mu.Unlock()
+	return p
+}
+
+func (p *progress) add(outcome int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch outcome {
+	case outcomeImported:
+		p.counts.imported++
+	case outcomeSkipped:
+		p.counts.skipped++
+	case outcomeFailed:
+		p.counts.failed++
+	}
+	p.draw(false)
+}
+
+// logf prints a message above the bar.
+func (p *progress) logf(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.terminal {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	p.draw(true)
+}
+
+// finish draws the final state and ends the bar's line.
+func (p *progress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	done := p.counts.imported + p.counts.skipped + p.counts.failed
+	if p.terminal || done != p.drawnDone {
+		p.draw(true)
+	}
+	if p.terminal {
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+func (p *progress) stats() stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.counts
+}
+
+// draw must be called with mu held.
+func (p *progress) draw(force bool) {
+	interval := logInterval
+	if p.terminal {
+		interval = redrawInterval
+	}
+	now := time.Now()
+	if !force && now.Sub(p.lastDraw) < interval {
+		return
+	}
+	done := p.counts.imported + p.counts.skipped + p.counts.failed
+	p.lastDraw, p.drawnDone = now, done
+
+	fraction := 1.0
+	if p.total > 0 {
+		fraction = float64(done) / float64(p.total)
+	}
+	status := fmt.Sprintf("%3.0f%% %d/%d  imported %d  skipped %d  failed %d",
+		fraction*100, done, p.total, p.counts.imported, p.counts.skipped, p.counts.failed)
+	if elapsed := now.Sub(p.start); done > 0 && done < p.total {
+		remaining := time.Duration(float64(elapsed) / float64(done) * float64(p.total-done))
+		status += "  ETA " + remaining.Round(time.Second).String()
+	}
+
+	if !p.terminal {
+		fmt.Fprintln(os.Stderr, status)
+		return
+	}
+	filled := int(fraction * barWidth)
+	bar := strings.Repeat("=", filled)
+	if filled < barWidth {
+		bar += ">" + strings.Repeat(" ", barWidth-filled-1)
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K[%s] %s", bar, status)
+}
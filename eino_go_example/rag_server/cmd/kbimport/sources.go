@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"rag-backend/docparse"
+)
+
+// maxSitemapDepth bounds how far sitemap indexes are followed.
+const maxSitemapDepth = 3
+
+// item is one document to import: a file under the imported directory or a
+// URL.
+type item struct {
+	// key identifies the document in the checkpoint: its slash-separated
+	// path relative to the directory, or its URL
+	key  string
+	path string
+	url  string
+	name string
+}
+
+// docID is derived from the key and the hash of the content, so a changed
+// document is uploaded alongside the version imported before rather than
+// over it.
+func (it *item) docID(hash string) string {
+	sum := sha256.Sum256([]byte(it.key + "\x00" + hash))
+	return "doc_" + hex.EncodeToString(sum[:8])
+}
+
+// load returns the content of the document and, for URLs, its declared
+// content type.
+func (it *item) load(ctx context.Context) ([]byte, string, error) {
+	if it.url != "" {
+		return docparse.Fetch(ctx, it.url)
+	}
+	content, err := os.ReadFile(it.path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", it.path, err)
+	}
+	return content, "", nil
+}
+
+// walkDir lists the files under dir with one of extensions, skipping hidden
+// files and directories.
+func walkDir(dir string, extensions map[string]bool) ([]*item, error) {
+	var items []*item
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !extensions[strings.ToLower(filepath.Ext(p))] {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		items = append(items, &item{key: rel, path: p, name: rel})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
+	}
+	return items, nil
+}
+
+// readURLList reads the URLs of a sitemap, following sitemap indexes, or of
+// a list with one URL per line. src is a file or an http(s) URL.
+func readURLList(ctx context.Context, src string) ([]*item, error) {
+	seen := make(map[string]bool)
+	var items []*item
+	var read func(src string, depth int) error
+	read = func(src string, depth int) error {
+		content, err := loadList(ctx, src)
+		if err != nil {
+			return err
+		}
+
+		var locs []string
+		if trimmed := bytes.TrimSpace(content); bytes.HasPrefix(trimmed, []byte("<")) {
+			var sitemap struct {
+				URLs []struct {
+					Loc string `xml:"loc"`
+				} `xml:"url"`
+				Sitemaps []struct {
+					Loc string `xml:"loc"`
+				} `xml:"sitemap"`
+			}
+			if err := xml.Unmarshal(trimmed, &sitemap); err != nil {
+				return fmt.Errorf("failed to parse sitemap %s: %w", src, err)
+			}
+			for _, s := range sitemap.Sitemaps {
+				if depth >= maxSitemapDepth {
+					return fmt.Errorf("sitemap indexes nested deeper than %d at %s", maxSitemapDepth, s.Loc)
+				}
+				if err := read(strings.TrimSpace(s.Loc), depth+1); err != nil {
+					return err
+				}
+			}
+			for _, u := range sitemap.URLs {
+				locs = append(locs, u.Loc)
+			}
+		} else {
+			for _, line := range strings.Split(string(content), "\n") {
+				if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+					locs = append(locs, line)
+				}
+			}
+		}
+
+		for _, loc := range locs {
+			loc = strings.TrimSpace(loc)
+			u, err := url.Parse(loc)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("not an http or https URL in %s: %q", src, loc)
+			}
+			if seen[loc] {
+				continue
+			}
+			seen[loc] = true
+			name := path.Base(u.Path)
+			if name == "/" || name == "." {
+				name = loc
+			}
+			items = append(items, &item{key: loc, url: loc, name: name})
+		}
+		return nil
+	}
+	if err := read(src, 0); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+func loadList(ctx context.Context, src string) ([]byte, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		content, _, err := docparse.Fetch(ctx, src)
+		return content, err
+	}
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	return content, nil
+}
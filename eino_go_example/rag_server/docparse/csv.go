@@ -0,0 +1,48 @@
+package docparse
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/cloudwego/eino/components/document/parser"
+	"github.com/cloudwego/eino/schema"
+)
+
+// csvRowsPerSection is how many data rows share a section; every section
+// repeats the header so its chunks stand on their own.
+const csvRowsPerSection = 50
+
+// csvParser reads the first row of a CSV file as the header and renders the
+// data rows as markdown tables.
+type csvParser struct{}
+
+func (csvParser) Parse(ctx context.Context, reader io.Reader, opts ...parser.Option) ([]*schema.Document, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header, data := records[0], records[1:]
+	var docs []*schema.Document
+	for start := 0; start < len(data) || start == 0; start += csvRowsPerSection {
+		end := min(start+csvRowsPerSection, len(data))
+		rows := append([][]string{header}, data[start:end]...)
+		docs = append(docs, &schema.Document{
+			Content: markdownTable(rows),
+			MetaData: map[string]interface{}{
+				MetaTableColumns: header,
+				// File rows, counting the header as row 1
+				MetaTableRows: fmt.Sprintf("%d-%d", start+2, end+1),
+			},
+		})
+	}
+	return docs, nil
+}
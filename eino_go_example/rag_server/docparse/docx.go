@@ -0,0 +1,137 @@
+package docparse
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/components/document/parser"
+	"github.com/cloudwego/eino/schema"
+)
+
+// docxParser reads the main part of a Word document, word/document.xml,
+// splitting at paragraphs styled Title or Heading 1-9 and lifting out tables.
+type docxParser struct{}
+
+func (docxParser) Parse(ctx context.Context, reader io.Reader, opts ...parser.Option) ([]*schema.Document, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("not a docx file: %w", err)
+	}
+	part, err := archive.Open("word/document.xml")
+	if err != nil {
+		return nil, fmt.Errorf("not a docx file: %w", err)
+	}
+	defer part.Close()
+
+	var (
+		s       sections
+		decoder = xml.NewDecoder(part)
+		// The paragraph being read and its heading level, 0 for body text
+		paragraph strings.Builder
+		level     int
+		// Rows of the open table; nested tables are read as cell text
+		rows  [][]string
+		depth int
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read document.xml: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				paragraph.Reset()
+				level = 0
+			case "pStyle":
+				level = headingLevel(attr(t, "val"))
+			case "tab":
+				// Tab stop definitions in paragraph properties carry a position
+				if attr(t, "pos") == "" {
+					paragraph.WriteString("\t")
+				}
+			case "br", "cr":
+				paragraph.WriteString("\n")
+			case "t":
+				var text string
+				if err := decoder.DecodeElement(&text, &t); err != nil {
+					return nil, fmt.Errorf("failed to read document.xml: %w", err)
+				}
+				paragraph.WriteString(text)
+			case "tbl":
+				depth++
+			case "tr":
+				if depth == 1 {
+					rows = append(rows, nil)
+				}
+			case "tc":
+				if depth == 1 && len(rows) > 0 {
+					rows[len(rows)-1] = append(rows[len(rows)-1], "")
+				}
+			}
+
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				text := paragraph.String()
+				switch {
+				case depth > 0:
+					// Paragraphs of a cell join into the cell
+					if len(rows) > 0 && len(rows[len(rows)-1]) > 0 {
+						row := rows[len(rows)-1]
+						row[len(row)-1] = strings.TrimSpace(row[len(row)-1] + " " + text)
+					}
+				case level > 0:
+					s.heading(level, text)
+				default:
+					s.paragraph(text)
+				}
+			case "tbl":
+				if depth--; depth == 0 {
+					s.table(rows)
+					rows = nil
+				}
+			}
+		}
+	}
+	return s.result(), nil
+}
+
+// headingLevel maps a paragraph style ID such as "Heading2" or "Title" to a
+// heading level, 0 for other styles.
+func headingLevel(style string) int {
+	lower := strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	if lower == "title" {
+		return 1
+	}
+	if rest, ok := strings.CutPrefix(lower, "heading"); ok {
+		if n, err := strconv.Atoi(rest); err == nil && n >= 1 && n <= 9 {
+			return min(n, 6)
+		}
+	}
+	return 0
+}
+
+func attr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package docparse
+
+import (
+	"context"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/cloudwego/eino/components/document/parser"
+	"github.com/cloudwego/eino/schema"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlParser splits the visible text of an HTML page at h1-h6 and lifts out
+// tables. Scripts, styles and navigation are dropped.
+type htmlParser struct{}
+
+func (htmlParser) Parse(ctx context.Context, reader io.Reader, opts ...parser.Option) ([]*schema.Document, error) {
+	root, err := html.Parse(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		s    sections
+		text strings.Builder
+	)
+	endParagraph := func() {
+		s.paragraph(text.String())
+		text.Reset()
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.TextNode:
+			// Keep the whitespace between inline elements, collapsed
+			t := collapseSpace(n.Data)
+			if strings.TrimLeftFunc(n.Data, unicode.IsSpace) != n.Data {
+				t = " " + t
+			}
+			if t != " " && strings.TrimRightFunc(n.Data, unicode.IsSpace) != n.Data {
+				t += " "
+			}
+			if current := text.String(); current == "" || strings.HasSuffix(current, " ") || strings.HasSuffix(current, "\n") {
+				t = strings.TrimLeft(t, " ")
+			}
+			text.WriteString(t)
+			return
+		case html.ElementNode:
+			switch n.DataAtom {
+			case atom.Script, atom.Style, atom.Noscript, atom.Template, atom.Nav, atom.Head:
+				return
+			case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+				endParagraph()
+				s.heading(int(n.Data[1]-'0'), nodeText(n))
+				return
+			case atom.Table:
+				endParagraph()
+				s.table(tableRows(n))
+				return
+			case atom.Br:
+				text.WriteString("\n")
+				return
+			}
+		}
+
+		block := n.Type == html.ElementNode && isBlock(n.DataAtom)
+		if block {
+			endParagraph()
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if block {
+			endParagraph()
+		}
+	}
+	walk(root)
+	endParagraph()
+	return s.result(), nil
+}
+
+func isBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.Div, atom.Li, atom.Ul, atom.Ol, atom.Section, atom.Article, atom.Main,
+		atom.Header, atom.Footer, atom.Aside, atom.Blockquote, atom.Pre, atom.Dl, atom.Dt, atom.Dd,
+		atom.Figure, atom.Figcaption, atom.Form, atom.Body:
+		return true
+	default:
+		return false
+	}
+}
+
+// tableRows returns the cell text of each row of a table, not descending
+// into nested tables.
+func tableRows(table *html.Node) [][]string {
+	var rows [][]string
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Tr:
+				var row []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+						row = append(row, nodeText(cell))
+					}
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(child)
+			}
+		}
+	}
+	walk(table)
+	return rows
+}
+
+// nodeText is the visible text inside n with whitespace collapsed.
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data + " ")
+			return
+		}
+		if n.DataAtom == atom.Script || n.DataAtom == atom.Style {
+			return
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return collapseSpace(b.String())
+}
@@ -0,0 +1,118 @@
+package docparse
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/cloudwego/eino/components/document/parser"
+	"github.com/cloudwego/eino/schema"
+)
+
+// markdownParser splits Markdown at ATX headings ("# Title") and lifts out
+// pipe tables. Fenced code blocks are kept as they are.
+type markdownParser struct{}
+
+func (markdownParser) Parse(ctx context.Context, reader io.Reader, opts ...parser.Option) ([]*schema.Document, error) {
+	var (
+		s       sections
+		block   []string
+		rows    [][]string
+		fenced  bool
+		scanner = bufio.NewScanner(reader)
+	)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	endBlock := func() {
+		s.paragraph(strings.Join(block, "\n"))
+		block = nil
+	}
+	endTable := func() {
+		s.table(rows)
+		rows = nil
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fenced = !fenced
+		}
+		if fenced || strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			block = append(block, line)
+			continue
+		}
+
+		if level, title, ok := atxHeading(trimmed); ok {
+			endBlock()
+			endTable()
+			s.heading(level, title)
+			continue
+		}
+
+		if cells, ok := tableRow(trimmed); ok {
+			if isDelimiterRow(cells) {
+				continue
+			}
+			if rows == nil {
+				endBlock()
+			}
+			rows = append(rows, cells)
+			continue
+		}
+		if rows != nil {
+			endTable()
+		}
+
+		if trimmed == "" {
+			endBlock()
+			continue
+		}
+		block = append(block, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	endBlock()
+	endTable()
+	return s.result(), nil
+}
+
+// atxHeading parses "## Title ##" into level 2 and "Title".
+func atxHeading(line string) (int, string, bool) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ' && line[level] != '\t') {
+		return 0, "", false
+	}
+	title := strings.TrimRight(strings.TrimSpace(line[level:]), "#")
+	return level, strings.TrimSpace(title), true
+}
+
+// tableRow splits "| a | b |" into its cells.
+func tableRow(line string) ([]string, bool) {
+	if !strings.HasPrefix(line, "|") || len(line) < 2 {
+		return nil, false
+	}
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = strings.TrimSpace(cell)
+	}
+	return cells, true
+}
+
+// isDelimiterRow reports whether cells are the "| --- | :-: |" row under a
+// table header.
+func isDelimiterRow(cells []string) bool {
+	for _, cell := range cells {
+		if strings.Trim(cell, ":-") != "" || !strings.Contains(cell, "-") {
+			return false
+		}
+	}
+	return true
+}
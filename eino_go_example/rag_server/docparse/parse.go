@@ -0,0 +1,185 @@
+// Package docparse extracts text from uploaded files locally, with pure-Go
+// eino parsers for PDF, DOCX, Markdown, HTML, CSV and plain text. A file
+// becomes a list of sections whose metadata records where the text came
+// from: the enclosing headings, the PDF page or the table columns.
+package docparse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/components/document/parser"
+	"github.com/cloudwego/eino/schema"
+)
+
+// Section metadata keys
+const (
+	// MetaHeading is the path of headings enclosing a section, "A > B".
+	MetaHeading = "heading"
+	// MetaPage is the 1-based page of a PDF section, an int.
+	MetaPage = "page"
+	// MetaTableColumns is the header row of a table section, a []string.
+	MetaTableColumns = "table_columns"
+	// MetaTableRows is the range of CSV rows in a section, "2-51".
+	MetaTableRows = "table_rows"
+)
+
+// ErrUnsupportedType is returned for files that are neither text nor one of
+// the parsed formats.
+var ErrUnsupportedType = errors.New("unsupported file type")
+
+// Extensions of the formats Parse understands, by content type
+var extensions = map[string]string{
+	"application/pdf": ".pdf",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"text/markdown":   ".md",
+	"text/x-markdown": ".md",
+	"text/html":       ".html",
+	"text/csv":        ".csv",
+	"text/plain":      ".txt",
+}
+
+var extParser = sync.OnceValues(func() (*parser.ExtParser, error) {
+	pdf, err := newPDFParser(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return parser.NewExtParser(context.Background(), &parser.ExtParserConfig{
+		Parsers: map[string]parser.Parser{
+			".pdf":      pdf,
+			".docx":     docxParser{},
+			".md":       markdownParser{},
+			".markdown": markdownParser{},
+			".html":     htmlParser{},
+			".htm":      htmlParser{},
+			".csv":      csvParser{},
+		},
+		FallbackParser: parser.TextParser{},
+	})
+})
+
+// DetectType returns the extension of the format of content, such as ".pdf",
+// going by the file name, then the declared content type, then the content
+// itself. Unrecognised text is ".txt".
+func DetectType(name, contentType string, content []byte) (string, error) {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".htm" || ext == ".markdown" {
+		return ext, nil
+	}
+	for _, known := range extensions {
+		if ext == known {
+			return ext, nil
+		}
+	}
+
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	if known, ok := extensions[strings.TrimSpace(strings.ToLower(mediaType))]; ok {
+		return known, nil
+	}
+
+	switch {
+	case bytes.HasPrefix(content, []byte("%PDF-")):
+		return ".pdf", nil
+	case bytes.HasPrefix(content, []byte("PK\x03\x04")) && bytes.Contains(content, []byte("word/")):
+		return ".docx", nil
+	}
+	if sniffed, _, _ := strings.Cut(http.DetectContentType(content), ";"); sniffed == "text/html" {
+		return ".html", nil
+	}
+	if utf8.Valid(content) {
+		return ".txt", nil
+	}
+	if ext == "" {
+		ext = "binary content"
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedType, ext)
+}
+
+// Parse extracts the sections of a file. name and contentType may be empty;
+// see DetectType. Every section carries the metadata in meta.
+func Parse(ctx context.Context, name, contentType string, content []byte, meta map[string]interface{}) ([]*schema.Document, error) {
+	ext, err := DetectType(name, contentType, content)
+	if err != nil {
+		return nil, err
+	}
+	p, err := extParser()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create parser: %w", err)
+	}
+
+	// The extension picks the parser, case-sensitively, so give the name the
+	// detected one in lower case
+	uri := name
+	if strings.EqualFold(filepath.Ext(name), ext) {
+		uri = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	uri += ext
+	docs, err := p.Parse(ctx, bytes.NewReader(content), parser.WithURI(uri), parser.WithExtraMeta(meta))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ext, err)
+	}
+
+	sections := docs[:0]
+	for _, doc := range docs {
+		if doc != nil && strings.TrimSpace(doc.Content) != "" {
+			delete(doc.MetaData, parser.MetaKeySource)
+			sections = append(sections, doc)
+		}
+	}
+	return sections, nil
+}
+
+// MaxFetchSize caps the size of a document downloaded by Fetch.
+const MaxFetchSize = 50 << 20
+
+var fetchClient = &http.Client{Timeout: 60 * time.Second}
+
+// CheckURL returns an error unless rawURL is an http or https URL that
+// Fetch can download from.
+func CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: not an http or https URL: %s", ErrUnsupportedType, rawURL)
+	}
+	return nil
+}
+
+// Fetch downloads the document at an http or https URL and returns it with
+// its declared content type.
+func Fetch(ctx context.Context, rawURL string) ([]byte, string, error) {
+	if err := CheckURL(rawURL); err != nil {
+		return nil, "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("failed to fetch %s: status %d", rawURL, resp.StatusCode)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, MaxFetchSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	if len(content) > MaxFetchSize {
+		return nil, "", fmt.Errorf("%s is larger than %d bytes", rawURL, MaxFetchSize)
+	}
+	return content, resp.Header.Get("Content-Type"), nil
+}
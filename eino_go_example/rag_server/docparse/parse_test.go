@@ -0,0 +1,297 @@
+package docparse
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDetectType(t *testing.T) {
+	tests := []struct {
+		name        string
+		fileName    string
+		contentType string
+		content     string
+		want        string
+		wantErr     error
+	}{
+		{name: "extension", fileName: "guide.PDF", content: "text", want: ".pdf"},
+		{name: "htm", fileName: "index.htm", want: ".htm"},
+		{name: "markdown", fileName: "notes.markdown", want: ".markdown"},
+		{name: "content type", fileName: "export", contentType: "text/csv; charset=utf-8", content: "a,b", want: ".csv"},
+		{name: "unknown extension", fileName: "notes.rst", contentType: "text/markdown", want: ".md"},
+		{name: "pdf magic", content: "%PDF-1.7", want: ".pdf"},
+		{name: "docx magic", content: "PK\x03\x04[Content_Types].xml word/document.xml", want: ".docx"},
+		{name: "sniffed html", content: "<!DOCTYPE html><p>hi</p>", want: ".html"},
+		{name: "text", fileName: "README", content: "plain text", want: ".txt"},
+		{name: "binary", fileName: "image.png", content: "\x89PNG\r\n\x1a\n\xff\xfe", wantErr: ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectType(tt.fileName, tt.contentType, []byte(tt.content))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DetectType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// section is the part of a parsed section the tests compare.
+type section struct {
+	content string
+	meta    map[string]interface{}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		content  []byte
+		want     []section
+	}{
+		{
+			name:     "markdown headings",
+			fileName: "guide.md",
+			content:  []byte("Intro text.\n\n# Setup\n\nInstall it.\n\n## Linux ##\n\nUse apt.\n\n### \n\n# Usage\nRun it."),
+			want: []section{
+				{"Intro text.", map[string]interface{}{}},
+				{"Setup\n\nInstall it.", map[string]interface{}{MetaHeading: "Setup"}},
+				{"Linux\n\nUse apt.", map[string]interface{}{MetaHeading: "Setup > Linux"}},
+				{"Usage\n\nRun it.", map[string]interface{}{MetaHeading: "Usage"}},
+			},
+		},
+		{
+			name:     "markdown skipped level",
+			fileName: "guide.md",
+			content:  []byte("# A\n\n### C\n\ntext"),
+			want: []section{
+				{"A", map[string]interface{}{MetaHeading: "A"}},
+				{"C\n\ntext", map[string]interface{}{MetaHeading: "A > C"}},
+			},
+		},
+		{
+			name:     "markdown fenced code",
+			fileName: "guide.md",
+			content:  []byte("# Build\n\n```sh\n# not a heading\n\nmake\n```"),
+			want: []section{
+				{"Build\n\n```sh\n# not a heading\n\nmake\n```", map[string]interface{}{MetaHeading: "Build"}},
+			},
+		},
+		{
+			name:     "markdown table",
+			fileName: "guide.md",
+			content:  []byte("# Limits\n\nSee below.\n| Name | Max |\n| :--- | --: |\n| size | 5 |\nAfter."),
+			want: []section{
+				{"Limits\n\nSee below.", map[string]interface{}{MetaHeading: "Limits"}},
+				{"| Name | Max |\n| --- | --- |\n| size | 5 |", map[string]interface{}{MetaHeading: "Limits", MetaTableColumns: []string{"Name", "Max"}}},
+				{"After.", map[string]interface{}{MetaHeading: "Limits"}},
+			},
+		},
+		{
+			name:     "html",
+			fileName: "page.html",
+			content: []byte(`<html><head><title>Skipped</title></head><body>
+<nav>Home | Docs</nav>
+<h1>Guide</h1><p>Some <b>bold</b>
+text.</p><script>alert(1)</script>
+<h2>Table</h2>
+<table><tr><th>Key</th><th>Value</th></tr><tr><td>a|b</td><td>1</td></tr></table>
+<div>Line one<br>line two</div>
+</body></html>`),
+			want: []section{
+				{"Guide\n\nSome bold text.", map[string]interface{}{MetaHeading: "Guide"}},
+				{"Table", map[string]interface{}{MetaHeading: "Guide > Table"}},
+				{"| Key | Value |\n| --- | --- |\n| a\\|b | 1 |", map[string]interface{}{MetaHeading: "Guide > Table", MetaTableColumns: []string{"Key", "Value"}}},
+				{"Line one\nline two", map[string]interface{}{MetaHeading: "Guide > Table"}},
+			},
+		},
+		{
+			name:     "csv",
+			fileName: "data.csv",
+			content:  []byte("name,age\nann,30\nbob\n"),
+			want: []section{
+				{"| name | age |\n| --- | --- |\n| ann | 30 |\n| bob |  |", map[string]interface{}{MetaTableColumns: []string{"name", "age"}, MetaTableRows: "2-3"}},
+			},
+		},
+		{
+			name:     "csv header only",
+			fileName: "data.csv",
+			content:  []byte("name,age\n"),
+			want: []section{
+				{"| name | age |\n| --- | --- |", map[string]interface{}{MetaTableColumns: []string{"name", "age"}, MetaTableRows: "2-1"}},
+			},
+		},
+		{
+			name:     "docx",
+			fileName: "report.docx",
+			content: docx(t, `<w:p><w:pPr><w:pStyle w:val="Title"/></w:pPr><w:r><w:t>Report</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>First</w:t><w:tab/><w:t>line</w:t><w:br/><w:t>second line</w:t></w:r></w:p>`+
+				`<w:p><w:pPr><w:pStyle w:val="Heading2"/><w:tabs><w:tab w:pos="720"/></w:tabs></w:pPr><w:r><w:t>Numbers</w:t></w:r></w:p>`+
+				`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Q</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Sales</w:t></w:r></w:p></w:tc></w:tr>`+
+				`<w:tr><w:tc><w:p><w:r><w:t>Q1</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>10</w:t></w:r></w:p><w:p><w:r><w:t>units</w:t></w:r></w:p></w:tc></w:tr></w:tbl>`),
+			want: []section{
+				{"Report\n\nFirst\tline\nsecond line", map[string]interface{}{MetaHeading: "Report"}},
+				{"Numbers", map[string]interface{}{MetaHeading: "Report > Numbers"}},
+				{"| Q | Sales |\n| --- | --- |\n| Q1 | 10 units |", map[string]interface{}{MetaHeading: "Report > Numbers", MetaTableColumns: []string{"Q", "Sales"}}},
+			},
+		},
+		{
+			name:     "uppercase markdown",
+			fileName: "NOTES.MD",
+			content:  []byte("# A\n\none\n\n# B\n\ntwo"),
+			want: []section{
+				{"A\n\none", map[string]interface{}{MetaHeading: "A"}},
+				{"B\n\ntwo", map[string]interface{}{MetaHeading: "B"}},
+			},
+		},
+		{
+			name:     "uppercase html",
+			fileName: "Index.HTM",
+			content:  []byte("<h1>Title</h1><p>Body</p>"),
+			want:     []section{{"Title\n\nBody", map[string]interface{}{MetaHeading: "Title"}}},
+		},
+		{
+			name:     "uppercase csv",
+			fileName: "DATA.CSV",
+			content:  []byte("a,b\n1,2\n"),
+			want:     []section{{"| a | b |\n| --- | --- |\n| 1 | 2 |", map[string]interface{}{MetaTableColumns: []string{"a", "b"}, MetaTableRows: "2-2"}}},
+		},
+		{
+			name:     "uppercase docx",
+			fileName: "X.DOCX",
+			content:  docx(t, `<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Scope</w:t></w:r></w:p><w:p><w:r><w:t>Body</w:t></w:r></w:p>`),
+			want:     []section{{"Scope\n\nBody", map[string]interface{}{MetaHeading: "Scope"}}},
+		},
+		{
+			name:    "text",
+			content: []byte("just text"),
+			want:    []section{{"just text", map[string]interface{}{}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := Parse(context.Background(), tt.fileName, "", tt.content, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]section, len(docs))
+			for i, doc := range docs {
+				got[i] = section{doc.Content, doc.MetaData}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() =\n%s\nwant\n%s", formatSections(got), formatSections(tt.want))
+			}
+		})
+	}
+}
+
+func TestParseExtraMeta(t *testing.T) {
+	docs, err := Parse(context.Background(), "guide.md", "", []byte("# A\n\none\n\n# B\n\ntwo"), map[string]interface{}{"source": "wiki"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("got %d sections, want 2", len(docs))
+	}
+	for _, doc := range docs {
+		if doc.MetaData["source"] != "wiki" {
+			t.Errorf("section %q metadata = %v, want source wiki", doc.Content, doc.MetaData)
+		}
+	}
+}
+
+func TestParsePDFPages(t *testing.T) {
+	for _, name := range []string{"", "report.pdf", "REPORT.PDF"} {
+		t.Run(name, func(t *testing.T) {
+			testParsePDFPages(t, name)
+		})
+	}
+}
+
+func testParsePDFPages(t *testing.T, name string) {
+	docs, err := Parse(context.Background(), name, "", pdfFile("first page", "second page"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("got %d sections, want 2", len(docs))
+	}
+	for i, want := range []string{"first page", "second page"} {
+		if !strings.Contains(docs[i].Content, want) {
+			t.Errorf("page %d = %q, want it to contain %q", i+1, docs[i].Content, want)
+		}
+		if docs[i].MetaData[MetaPage] != i+1 {
+			t.Errorf("page %d metadata = %v", i+1, docs[i].MetaData)
+		}
+	}
+}
+
+func TestParseInvalidDocx(t *testing.T) {
+	if _, err := Parse(context.Background(), "report.docx", "", []byte("not a zip"), nil); err == nil {
+		t.Error("Parse() of an invalid docx succeeded")
+	}
+}
+
+// docx returns a Word document whose body is the given WordprocessingML.
+func docx(t *testing.T, body string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	part, err := archive.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintf(part, `<?xml version="1.0" encoding="UTF-8"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>%s</w:body></w:document>`, body)
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pdfFile returns a PDF with one line of Helvetica text per page.
+func pdfFile(pages ...string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // the page tree, once the page numbers are known
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var kids []string
+	for _, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", len(objects)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages))
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return b.Bytes()
+}
+
+func formatSections(sections []section) string {
+	var b strings.Builder
+	for _, s := range sections {
+		fmt.Fprintf(&b, "  %q %v\n", s.content, s.meta)
+	}
+	return b.String()
+}
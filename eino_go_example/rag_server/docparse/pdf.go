@@ -0,0 +1,35 @@
+package docparse
+
+import (
+	"context"
+	"io"
+
+	"github.com/cloudwego/eino-ext/components/document/parser/pdf"
+	"github.com/cloudwego/eino/components/document/parser"
+	"github.com/cloudwego/eino/schema"
+)
+
+// pdfParser splits a PDF into one section per page.
+type pdfParser struct {
+	pages *pdf.PDFParser
+}
+
+func newPDFParser(ctx context.Context) (*pdfParser, error) {
+	pages, err := pdf.NewPDFParser(ctx, &pdf.Config{ToPages: true})
+	if err != nil {
+		return nil, err
+	}
+	return &pdfParser{pages: pages}, nil
+}
+
+func (p *pdfParser) Parse(ctx context.Context, reader io.Reader, opts ...parser.Option) ([]*schema.Document, error) {
+	docs, err := p.pages.Parse(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+	// The pages share one metadata map, so give each its own
+	for i, doc := range docs {
+		doc.MetaData = map[string]interface{}{MetaPage: i + 1}
+	}
+	return docs, nil
+}
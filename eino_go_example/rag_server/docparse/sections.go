@@ -0,0 +1,114 @@
+package docparse
+
+import (
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// sections collects the text of a structured document into one section per
+// heading, and one per table.
+type sections struct {
+	headings []string // by level, empty for skipped levels
+	text     strings.Builder
+	docs     []*schema.Document
+}
+
+// heading starts a section under a heading of level 1 to 6.
+func (s *sections) heading(level int, title string) {
+	title = collapseSpace(title)
+	if title == "" {
+		return
+	}
+	s.flush()
+	level = min(max(level, 1), 6)
+	for len(s.headings) < level {
+		s.headings = append(s.headings, "")
+	}
+	s.headings = append(s.headings[:level-1], title)
+	s.text.WriteString(title + "\n\n")
+}
+
+// paragraph adds a block of text to the current section.
+func (s *sections) paragraph(text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		s.text.WriteString(text + "\n\n")
+	}
+}
+
+// table adds a table as a section of its own. The first row is the header.
+func (s *sections) table(rows [][]string) {
+	if len(rows) == 0 {
+		return
+	}
+	s.flush()
+	doc := &schema.Document{
+		Content:  markdownTable(rows),
+		MetaData: map[string]interface{}{MetaTableColumns: rows[0]},
+	}
+	if path := s.path(); path != "" {
+		doc.MetaData[MetaHeading] = path
+	}
+	s.docs = append(s.docs, doc)
+}
+
+// flush ends the current section.
+func (s *sections) flush() {
+	content := strings.TrimSpace(s.text.String())
+	s.text.Reset()
+	if content == "" {
+		return
+	}
+	doc := &schema.Document{Content: content, MetaData: map[string]interface{}{}}
+	if path := s.path(); path != "" {
+		doc.MetaData[MetaHeading] = path
+	}
+	s.docs = append(s.docs, doc)
+}
+
+func (s *sections) result() []*schema.Document {
+	s.flush()
+	return s.docs
+}
+
+func (s *sections) path() string {
+	var path []string
+	for _, heading := range s.headings {
+		if heading != "" {
+			path = append(path, heading)
+		}
+	}
+	return strings.Join(path, " > ")
+}
+
+// markdownTable renders rows as a markdown table, padding short rows.
+func markdownTable(rows [][]string) string {
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for i := 0; i < width; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = strings.ReplaceAll(collapseSpace(row[i]), "|", "\\|")
+			}
+			b.WriteString(" " + cell + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(rows[0])
+	b.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
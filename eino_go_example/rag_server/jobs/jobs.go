@@ -0,0 +1,154 @@
+// Package jobs runs background jobs on a bounded pool of workers, tracking
+// each job's progress through named stages. Jobs and their payloads are kept
+// in a bbolt file, so queued work survives a restart; jobs that were running
+// when the process stopped are queued again.
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Job states
+const (
+	StateQueued    = "queued"
+	StateRunning   = "running"
+	StateCompleted = "completed"
+	StateFailed    = "failed"
+	StateCanceled  = "canceled"
+)
+
+// Stage states. Stages a job never reports on are skipped.
+const (
+	StagePending   = "pending"
+	StageRunning   = "running"
+	StageCompleted = "completed"
+	StageFailed    = "failed"
+	StageSkipped   = "skipped"
+)
+
+var (
+	// ErrNotFound is returned for unknown job IDs.
+	ErrNotFound = errors.New("job not found")
+	// ErrInvalidState is returned when a job cannot be canceled or retried
+	// in its current state.
+	ErrInvalidState = errors.New("invalid job state")
+)
+
+// Job is the state of one job. Done and Total of a stage count the units of
+// work the stage has finished, such as chunks embedded.
+type Job struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name,omitempty"`
+	State    string   `json:"state"`
+	Stages   []*Stage `json:"stages"`
+	DocIDs   []string `json:"doc_ids,omitempty"`
+	Error    string   `json:"error,omitempty"`
+	Attempts int      `json:"attempts"`
+
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type Stage struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+	Error string `json:"error,omitempty"`
+}
+
+// Finished reports whether the job will not run again unless retried.
+func (j *Job) Finished() bool {
+	return j.State == StateCompleted || j.State == StateFailed || j.State == StateCanceled
+}
+
+func newJob(name string, stages []string) *Job {
+	now := time.Now().UTC()
+	job := &Job{ID: newJobID(), Name: name, CreatedAt: now, UpdatedAt: now}
+	job.reset(stages)
+	return job
+}
+
+// reset queues the job to run from its first stage.
+func (j *Job) reset(stages []string) {
+	j.State = StateQueued
+	j.Stages = make([]*Stage, len(stages))
+	for i, name := range stages {
+		j.Stages[i] = &Stage{Name: name, State: StagePending}
+	}
+	j.DocIDs, j.Error = nil, ""
+	j.StartedAt, j.FinishedAt = nil, nil
+}
+
+// report records progress on a stage, which is complete once done reaches
+// total. Stages before it that never ran are skipped; stages may overlap,
+// such as embedding and upserting in batches.
+func (j *Job) report(stage string, done, total int) {
+	for _, s := range j.Stages {
+		if s.Name == stage {
+			s.State, s.Done, s.Total = StageRunning, done, total
+			if total > 0 && done >= total {
+				s.State = StageCompleted
+			}
+			return
+		}
+		if s.State == StagePending {
+			s.State = StageSkipped
+		}
+	}
+}
+
+// finish ends the job in state. A failed job's running stage, or the stage
+// after the last one to complete, is marked failed.
+func (j *Job) finish(state string, err error) {
+	now := time.Now().UTC()
+	j.State, j.FinishedAt = state, &now
+	if err != nil {
+		j.Error = err.Error()
+	}
+
+	if state == StateCompleted {
+		for _, s := range j.Stages {
+			switch s.State {
+			case StageRunning:
+				s.State = StageCompleted
+			case StagePending:
+				s.State = StageSkipped
+			}
+		}
+		return
+	}
+	if j.StartedAt == nil {
+		return
+	}
+	var failed *Stage
+	for _, s := range j.Stages {
+		if s.State == StageRunning {
+			failed = s
+			break
+		}
+		if s.State == StagePending && failed == nil {
+			failed = s
+		}
+		if s.State == StageCompleted || s.State == StageSkipped {
+			failed = nil
+		}
+	}
+	if failed != nil {
+		failed.State, failed.Error = StageFailed, j.Error
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("job_%d", time.Now().UnixNano())
+	}
+	return "job_" + hex.EncodeToString(b)
+}
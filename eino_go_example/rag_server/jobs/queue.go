@@ -0,0 +1,290 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Defaults for Config
+const (
+	defaultWorkers   = 2
+	defaultRetention = 7 * 24 * time.Hour
+	pruneInterval    = time.Hour
+)
+
+var errCanceled = errors.New("job canceled")
+
+// ReportFunc records that a job has done done of total units of a stage.
+type ReportFunc func(stage string, done, total int)
+
+// Processor runs a job from its payload and returns the IDs of the
+// documents it produced. It should return promptly once ctx is canceled.
+type Processor func(ctx context.Context, payload []byte, report ReportFunc) (docIDs []string, err error)
+
+type Config struct {
+	// Path is the bbolt file jobs are kept in.
+	Path string
+	// Workers is how many jobs run at once; zero uses 2.
+	Workers int
+	// Stages names the stages of every job, in order.
+	Stages []string
+	// Retention is how long finished jobs are kept; zero uses a week.
+	Retention time.Duration
+	Process   Processor
+}
+
+// Queue stores jobs and runs them on a fixed pool of workers.
+type Queue struct {
+	store  *store
+	config Config
+	wake   chan struct{}
+
+	mu      sync.Mutex
+	running map[string]*runningJob
+}
+
+// runningJob is a job being processed by this process.
+type runningJob struct {
+	cancel context.CancelFunc
+	// done is closed once the processor has returned
+	done chan struct{}
+}
+
+// Open opens the job file at config.Path, queueing any jobs that were
+// interrupted. Jobs only run once Start is called.
+func Open(config Config) (*Queue, error) {
+	if config.Process == nil {
+		return nil, fmt.Errorf("a job processor is required")
+	}
+	if config.Workers <= 0 {
+		config.Workers = defaultWorkers
+	}
+	if config.Retention <= 0 {
+		config.Retention = defaultRetention
+	}
+
+	store, err := openStore(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	requeued, err := store.requeueRunning(config.Stages)
+	if err != nil {
+		store.close()
+		return nil, fmt.Errorf("failed to requeue interrupted jobs: %w", err)
+	}
+	if requeued > 0 {
+		log.Printf("Requeued %d interrupted jobs", requeued)
+	}
+
+	return &Queue{
+		store:   store,
+		config:  config,
+		wake:    make(chan struct{}, config.Workers),
+		running: make(map[string]*runningJob),
+	}, nil
+}
+
+// Start runs the workers until ctx is done. Jobs running then are left to
+// be requeued when the file is next opened.
+func (q *Queue) Start(ctx context.Context) {
+	for i := 0; i < q.config.Workers; i++ {
+		go q.work(ctx)
+	}
+	go q.prune(ctx)
+	q.signal()
+}
+
+// Close closes the job file.
+func (q *Queue) Close() error {
+	return q.store.close()
+}
+
+// Enqueue adds a job with its payload. name labels the job in its status.
+func (q *Queue) Enqueue(name string, payload []byte) (*Job, error) {
+	job := newJob(name, q.config.Stages)
+	if err := q.store.add(job, payload); err != nil {
+		return nil, fmt.Errorf("failed to store job: %w", err)
+	}
+	q.signal()
+	return job, nil
+}
+
+// Get returns the state of a job.
+func (q *Queue) Get(id string) (*Job, error) {
+	return q.store.get(id)
+}
+
+// Cancel stops a queued or running job. A running job stops at the next
+// point its processor checks for cancellation.
+func (q *Queue) Cancel(id string) (*Job, error) {
+	job, err := q.store.update(id, func(_ *bolt.Tx, job *Job) error {
+		if job.Finished() {
+			return fmt.Errorf("%w: job is %s", ErrInvalidState, job.State)
+		}
+		job.finish(StateCanceled, errCanceled)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	if running, ok := q.running[id]; ok {
+		running.cancel()
+	}
+	q.mu.Unlock()
+	return job, nil
+}
+
+// Retry queues a failed or canceled job again from its first stage. A
+// canceled job whose processor has not returned yet starts again once it has.
+func (q *Queue) Retry(id string) (*Job, error) {
+	job, err := q.store.update(id, func(tx *bolt.Tx, job *Job) error {
+		if job.State != StateFailed && job.State != StateCanceled {
+			return fmt.Errorf("%w: only failed or canceled jobs can be retried, job is %s", ErrInvalidState, job.State)
+		}
+		job.reset(q.config.Stages)
+		return push(tx, job.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	q.signal()
+	return job, nil
+}
+
+// signal wakes an idle worker, if one is waiting.
+func (q *Queue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		job, payload, err := q.store.claim()
+		if err != nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case <-q.wake:
+			case <-time.After(time.Minute):
+			}
+			continue
+		}
+		// Let another worker pick up the rest of the queue
+		q.signal()
+		q.run(ctx, job, payload)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, job *Job, payload []byte) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	current := &runningJob{cancel: cancel, done: make(chan struct{})}
+	q.mu.Lock()
+	previous := q.running[job.ID]
+	q.running[job.ID] = current
+	q.mu.Unlock()
+	defer func() {
+		close(current.done)
+		q.mu.Lock()
+		if q.running[job.ID] == current {
+			delete(q.running, job.ID)
+		}
+		q.mu.Unlock()
+	}()
+
+	// Updates only apply to this attempt, and not once the job is canceled
+	attempt := job.Attempts
+	update := func(fn func(tx *bolt.Tx, job *Job) error) {
+		_, err := q.store.update(job.ID, func(tx *bolt.Tx, current *Job) error {
+			if current.State != StateRunning || current.Attempts != attempt {
+				return errStale
+			}
+			return fn(tx, current)
+		})
+		if err != nil && err != errStale {
+			log.Printf("Failed to update job %s: %v", job.ID, err)
+		}
+	}
+	report := func(stage string, done, total int) {
+		update(func(_ *bolt.Tx, job *Job) error {
+			job.report(stage, done, total)
+			return nil
+		})
+	}
+
+	// A retried job may still be unwinding its canceled attempt; never run
+	// the processor twice at once for a job
+	if previous != nil {
+		select {
+		case <-previous.done:
+		case <-jobCtx.Done():
+		}
+	}
+
+	var (
+		docIDs []string
+		err    = jobCtx.Err()
+	)
+	if err == nil {
+		log.Printf("Job %s started (attempt %d): %s", job.ID, attempt, job.Name)
+		docIDs, err = q.config.Process(jobCtx, payload, report)
+	}
+	if ctx.Err() != nil {
+		// Shutting down: the job is requeued when the store is next opened
+		return
+	}
+
+	update(func(tx *bolt.Tx, job *Job) error {
+		if err != nil {
+			job.finish(StateFailed, err)
+			return nil
+		}
+		job.DocIDs = docIDs
+		job.finish(StateCompleted, nil)
+		// Completed jobs cannot be retried, so their payload is not needed
+		return tx.Bucket(payloadsBucket).Delete([]byte(job.ID))
+	})
+	if errors.Is(err, context.Canceled) {
+		log.Printf("Job %s canceled", job.ID)
+	} else if err != nil {
+		log.Printf("Job %s failed: %v", job.ID, err)
+	} else {
+		log.Printf("Job %s completed: %v", job.ID, docIDs)
+	}
+}
+
+// errStale rejects updates from an attempt that is no longer current.
+var errStale = errors.New("stale job update")
+
+func (q *Queue) prune(ctx context.Context) {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		if count, err := q.store.prune(time.Now().Add(-q.config.Retention)); err != nil {
+			log.Printf("Failed to prune jobs: %v", err)
+		} else if count > 0 {
+			log.Printf("Pruned %d finished jobs", count)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var testStages = []string{"parse", "upsert"}
+
+// openQueue opens the job file at path with two workers.
+func openQueue(t *testing.T, path string, process Processor) *Queue {
+	t.Helper()
+	q, err := Open(Config{Path: path, Workers: 2, Stages: testStages, Process: process})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// startQueue opens and starts a queue on a file of its own, stopping it when
+// the test ends.
+func startQueue(t *testing.T, process Processor) *Queue {
+	t.Helper()
+	q := openQueue(t, filepath.Join(t.TempDir(), "jobs.db"), process)
+	ctx, cancel := context.WithCancel(context.Background())
+	q.Start(ctx)
+	t.Cleanup(func() {
+		cancel()
+		q.Close()
+	})
+	return q
+}
+
+// waitState waits for a job to reach state.
+func waitState(t *testing.T, q *Queue, id, state string) *Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := q.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.State == state {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", id, job.State, state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func complete(ctx context.Context, payload []byte, report ReportFunc) ([]string, error) {
+	report("parse", 1, 1)
+	report("upsert", 1, 1)
+	return []string{string(payload)}, nil
+}
+
+func TestJobsSurviveReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	q := openQueue(t, path, complete)
+	job, err := q.Enqueue("guide.md", []byte("doc_1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	q = openQueue(t, path, complete)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		q.Close()
+	}()
+	if got, err := q.Get(job.ID); err != nil || got.State != StateQueued {
+		t.Fatalf("reopened job = %+v, %v; want it queued", got, err)
+	}
+
+	q.Start(ctx)
+	done := waitState(t, q, job.ID, StateCompleted)
+	if len(done.DocIDs) != 1 || done.DocIDs[0] != "doc_1" {
+		t.Errorf("doc IDs = %v, want the stored payload doc_1", done.DocIDs)
+	}
+	for _, stage := range done.Stages {
+		if stage.State != StageCompleted {
+			t.Errorf("stage %s is %s, want completed", stage.Name, stage.State)
+		}
+	}
+}
+
+func TestRunningJobsRequeuedOnOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	q := openQueue(t, path, complete)
+	job, err := q.Enqueue("guide.md", []byte("doc_1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A worker claims the job and reports progress, then the process dies
+	claimed, _, err := q.store.claim()
+	if err != nil || claimed == nil || claimed.ID != job.ID {
+		t.Fatalf("claim = %v, %v", claimed, err)
+	}
+	if _, err := q.store.update(job.ID, func(_ *bolt.Tx, job *Job) error {
+		job.report("parse", 1, 1)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+
+	q = openQueue(t, path, complete)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		q.Close()
+	}()
+	requeued, err := q.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requeued.State != StateQueued || requeued.Stages[0].State != StagePending || requeued.StartedAt != nil {
+		t.Fatalf("requeued job = %+v, want queued from its first stage", requeued)
+	}
+
+	q.Start(ctx)
+	done := waitState(t, q, job.ID, StateCompleted)
+	if done.Attempts != 2 {
+		t.Errorf("attempts = %d, want 2", done.Attempts)
+	}
+}
+
+func TestCancelQueuedJob(t *testing.T) {
+	var processed sync.Map
+	process := func(ctx context.Context, payload []byte, report ReportFunc) ([]string, error) {
+		processed.Store(string(payload), true)
+		return nil, nil
+	}
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	q := openQueue(t, path, process)
+	canceled, err := q.Enqueue("canceled", []byte("canceled"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, err := q.Enqueue("kept", []byte("kept"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := q.Cancel(canceled.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != StateCanceled || job.Stages[0].State != StagePending {
+		t.Errorf("canceled job = %+v", job)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		q.Close()
+	}()
+	q.Start(ctx)
+	waitState(t, q, kept.ID, StateCompleted)
+	if _, ok := processed.Load("canceled"); ok {
+		t.Error("canceled job was processed")
+	}
+	if job, _ := q.Get(canceled.ID); job.State != StateCanceled {
+		t.Errorf("canceled job is %s", job.State)
+	}
+	if _, err := q.Cancel(canceled.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("second cancel = %v, want ErrInvalidState", err)
+	}
+}
+
+func TestCancelRunningJob(t *testing.T) {
+	started := make(chan struct{})
+	returned := make(chan struct{})
+	q := startQueue(t, func(ctx context.Context, payload []byte, report ReportFunc) ([]string, error) {
+		defer close(returned)
+		report("parse", 1, 1)
+		close(started)
+		<-ctx.Done()
+		// Progress and the result of the canceled attempt are stale
+		report("upsert", 1, 1)
+		return []string{"doc_1"}, nil
+	})
+	job, err := q.Enqueue("guide.md", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if _, err := q.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	<-returned
+
+	// Give the worker time to try its final update
+	time.Sleep(50 * time.Millisecond)
+	got, err := q.Get(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.State != StateCanceled || len(got.DocIDs) != 0 {
+		t.Errorf("job = %+v, want canceled without doc IDs", got)
+	}
+	if got.Stages[1].State != StageFailed {
+		t.Errorf("upsert stage = %+v, want the stale report ignored and the stage failed", got.Stages[1])
+	}
+}
+
+func TestRetryFailedJob(t *testing.T) {
+	var attempts atomic.Int32
+	q := startQueue(t, func(ctx context.Context, payload []byte, report ReportFunc) ([]string, error) {
+		report("parse", 1, 1)
+		if attempts.Add(1) == 1 {
+			return nil, errors.New("upstream unavailable")
+		}
+		return complete(ctx, payload, report)
+	})
+	job, err := q.Enqueue("guide.md", []byte("doc_1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := waitState(t, q, job.ID, StateFailed)
+	if failed.Error != "upstream unavailable" || failed.Stages[1].State != StageFailed {
+		t.Errorf("failed job = %+v", failed)
+	}
+	if _, err := q.Retry(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	done := waitState(t, q, job.ID, StateCompleted)
+	if done.Attempts != 2 || done.Error != "" || len(done.DocIDs) != 1 {
+		t.Errorf("retried job = %+v", done)
+	}
+	if _, err := q.Retry(job.ID); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("retry of a completed job = %v, want ErrInvalidState", err)
+	}
+	if _, err := q.Retry("job_missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("retry of an unknown job = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRetryWhileCanceledRunUnwinds(t *testing.T) {
+	var (
+		calls, active, maxActive atomic.Int32
+		started                  = make(chan struct{})
+		unwind                   = make(chan struct{})
+	)
+	q := startQueue(t, func(ctx context.Context, payload []byte, report ReportFunc) ([]string, error) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			if m := maxActive.Load(); n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		if calls.Add(1) == 1 {
+			close(started)
+			<-ctx.Done()
+			// Slow to stop, as when finishing a batch
+			<-unwind
+			return nil, ctx.Err()
+		}
+		return complete(ctx, payload, report)
+	})
+	job, err := q.Enqueue("guide.md", []byte("doc_1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	if _, err := q.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Retry(job.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// The other worker claims the retry but waits for the first attempt
+	waitState(t, q, job.ID, StateRunning)
+	time.Sleep(50 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("processor called %d times while the canceled attempt unwinds, want 1", n)
+	}
+	close(unwind)
+
+	done := waitState(t, q, job.ID, StateCompleted)
+	if done.Attempts != 2 || len(done.DocIDs) != 1 {
+		t.Errorf("retried job = %+v", done)
+	}
+	if m := maxActive.Load(); m != 1 {
+		t.Errorf("%d processors ran at once for the job, want 1", m)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	q := openQueue(t, filepath.Join(t.TempDir(), "jobs.db"), complete)
+	defer q.Close()
+	old, err := q.Enqueue("old", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queued, err := q.Enqueue("queued", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Cancel(old.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := q.store.prune(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("pruned %d jobs, want 1", count)
+	}
+	if _, err := q.Get(old.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("pruned job lookup = %v, want ErrNotFound", err)
+	}
+	if _, err := q.Get(queued.ID); err != nil {
+		t.Errorf("unfinished job was pruned: %v", err)
+	}
+}
@@ -0,0 +1,230 @@
+package jobs
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets of the job file: job state by ID, payloads by job ID, and the IDs
+// of queued jobs by a sequence number, in the order they run.
+var (
+	jobsBucket     = []byte("jobs")
+	payloadsBucket = []byte("payloads")
+	queueBucket    = []byte("queue")
+)
+
+type store struct {
+	db *bolt.DB
+}
+
+func openStore(path string) (*store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{jobsBucket, payloadsBucket, queueBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create job buckets: %w", err)
+	}
+	return &store{db: db}, nil
+}
+
+func (s *store) close() error {
+	return s.db.Close()
+}
+
+// add stores a new job with its payload and queues it.
+func (s *store) add(job *Job, payload []byte) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(payloadsBucket).Put([]byte(job.ID), payload); err != nil {
+			return err
+		}
+		if err := putJob(tx, job); err != nil {
+			return err
+		}
+		return push(tx, job.ID)
+	})
+}
+
+func (s *store) get(id string) (*Job, error) {
+	var job *Job
+	err := s.db.View(func(tx *bolt.Tx) error {
+		var err error
+		job, err = getJob(tx, id)
+		return err
+	})
+	return job, err
+}
+
+// update applies fn to a job and saves it, unless fn fails.
+func (s *store) update(id string, fn func(tx *bolt.Tx, job *Job) error) (*Job, error) {
+	var job *Job
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		if job, err = getJob(tx, id); err != nil {
+			return err
+		}
+		if err := fn(tx, job); err != nil {
+			return err
+		}
+		job.UpdatedAt = time.Now().UTC()
+		return putJob(tx, job)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// claim takes the next queued job and marks it running. It returns a nil
+// job when the queue is empty.
+func (s *store) claim() (*Job, []byte, error) {
+	var (
+		job     *Job
+		payload []byte
+	)
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		queue := tx.Bucket(queueBucket)
+		for key, value := queue.Cursor().First(); key != nil; key, value = queue.Cursor().First() {
+			// bbolt memory is only valid in the transaction, and the entry not
+			// after it is deleted
+			id := string(value)
+			if err := queue.Delete(key); err != nil {
+				return err
+			}
+			next, err := getJob(tx, id)
+			if err == ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if next.State != StateQueued {
+				continue
+			}
+
+			now := time.Now().UTC()
+			next.State, next.StartedAt, next.UpdatedAt = StateRunning, &now, now
+			next.Attempts++
+			if err := putJob(tx, next); err != nil {
+				return err
+			}
+			job = next
+			payload = append([]byte(nil), tx.Bucket(payloadsBucket).Get([]byte(id))...)
+			return nil
+		}
+		return nil
+	})
+	return job, payload, err
+}
+
+// requeueRunning queues again, from their first stage, the jobs that were
+// running when the process stopped.
+func (s *store) requeueRunning(stages []string) (int, error) {
+	count := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var interrupted []*Job
+		err := tx.Bucket(jobsBucket).ForEach(func(_, value []byte) error {
+			var job Job
+			if err := json.Unmarshal(value, &job); err != nil {
+				return err
+			}
+			if job.State == StateRunning {
+				interrupted = append(interrupted, &job)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, job := range interrupted {
+			job.reset(stages)
+			job.UpdatedAt = time.Now().UTC()
+			if err := putJob(tx, job); err != nil {
+				return err
+			}
+			if err := push(tx, job.ID); err != nil {
+				return err
+			}
+		}
+		count = len(interrupted)
+		return nil
+	})
+	return count, err
+}
+
+// prune deletes jobs that finished before cutoff, with their payloads.
+func (s *store) prune(cutoff time.Time) (int, error) {
+	count := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		var expired [][]byte
+		err := tx.Bucket(jobsBucket).ForEach(func(key, value []byte) error {
+			var job Job
+			if err := json.Unmarshal(value, &job); err != nil {
+				return err
+			}
+			if job.Finished() && job.FinishedAt != nil && job.FinishedAt.Before(cutoff) {
+				expired = append(expired, append([]byte(nil), key...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, key := range expired {
+			if err := tx.Bucket(jobsBucket).Delete(key); err != nil {
+				return err
+			}
+			if err := tx.Bucket(payloadsBucket).Delete(key); err != nil {
+				return err
+			}
+		}
+		count = len(expired)
+		return nil
+	})
+	return count, err
+}
+
+// push appends a job to the queue.
+func push(tx *bolt.Tx, id string) error {
+	queue := tx.Bucket(queueBucket)
+	seq, err := queue.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return queue.Put(key, []byte(id))
+}
+
+func getJob(tx *bolt.Tx, id string) (*Job, error) {
+	value := tx.Bucket(jobsBucket).Get([]byte(id))
+	if value == nil {
+		return nil, ErrNotFound
+	}
+	var job Job
+	if err := json.Unmarshal(value, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job %s: %w", id, err)
+	}
+	return &job, nil
+}
+
+func putJob(tx *bolt.Tx, job *Job) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job %s: %w", job.ID, err)
+	}
+	return tx.Bucket(jobsBucket).Put([]byte(job.ID), value)
+}
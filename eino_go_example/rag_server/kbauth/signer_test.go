@@ -0,0 +1,65 @@
+package kbauth
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	signer := &Signer{AccessKey: "ak", SecretKey: "sk", Region: "cn-beijing", AccountID: "2100000000"}
+	body := []byte(`{"query":"hello"}`)
+
+	tests := []struct {
+		name    string
+		signer  Signer
+		tamper  func(req *http.Request) []byte
+		wantErr bool
+	}{
+		{name: "valid", signer: *signer},
+		{name: "wrong secret key", signer: Signer{AccessKey: "ak", SecretKey: "other", Region: "cn-beijing"}, wantErr: true},
+		{name: "wrong access key", signer: Signer{AccessKey: "other", SecretKey: "sk", Region: "cn-beijing"}, wantErr: true},
+		{name: "wrong region", signer: Signer{AccessKey: "ak", SecretKey: "sk", Region: "ap-southeast-1"}, wantErr: true},
+		{
+			name:    "body changed",
+			signer:  *signer,
+			tamper:  func(*http.Request) []byte { return []byte(`{"query":"bye"}`) },
+			wantErr: true,
+		},
+		{
+			name:   "path changed",
+			signer: *signer,
+			tamper: func(req *http.Request) []byte {
+				req.URL.Path = "/api/knowledge/doc/delete"
+				return body
+			},
+			wantErr: true,
+		},
+		{
+			name:   "unsigned",
+			signer: *signer,
+			tamper: func(req *http.Request) []byte {
+				req.Header.Del("Authorization")
+				return body
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://kb.example.com/api/knowledge/collection/search_knowledge", bytes.NewReader(body))
+			if err := tt.signer.Sign(req, body); err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			received := body
+			if tt.tamper != nil {
+				received = tt.tamper(req)
+			}
+			err := signer.Verify(req, received)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
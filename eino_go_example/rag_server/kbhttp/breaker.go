@@ -0,0 +1,70 @@
+package kbhttp
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// breaker opens after threshold consecutive failures. Once the cooldown has
+// passed it lets a single probe through: success closes it again, failure
+// reopens it for another cooldown.
+type breaker struct {
+	host      string
+	threshold int
+	cooldown  time.Duration
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+func (b *breaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) record(success bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	if success {
+		if b.failures >= b.threshold {
+			log.Printf("kbhttp: circuit breaker for %s closed", b.host)
+		}
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		if b.failures == b.threshold {
+			log.Printf("kbhttp: circuit breaker for %s opened after %d consecutive failures", b.host, b.failures)
+		}
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// abandon ends a call that neither succeeded nor failed, such as one the
+// caller cancelled, so a probe does not hold the breaker half-open.
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
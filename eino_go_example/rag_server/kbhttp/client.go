@@ -0,0 +1,229 @@
+// Package kbhttp is the HTTP client shared by the knowledge base backends:
+// a pooled transport, retries with exponential backoff on 429, 5xx and
+// transient network errors, and a per-host circuit breaker that fails fast
+// while a service is down.
+package kbhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrCircuitOpen is returned without contacting the host while its circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+type Config struct {
+	// Timeout bounds each attempt, not the whole call.
+	Timeout             time.Duration
+	MaxIdleConnsPerHost int
+	// MaxRetries is how many times a failed attempt is repeated.
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// BreakerThreshold consecutive failed calls to a host open its breaker
+	// for BreakerCooldown; zero disables the breaker.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// TLSConfig overrides the system TLS settings, see LoadTLSConfig.
+	TLSConfig *tls.Config
+}
+
+// DefaultConfig returns the settings used when none are configured.
+func DefaultConfig() Config {
+	return Config{
+		Timeout:             30 * time.Second,
+		MaxIdleConnsPerHost: 16,
+		MaxRetries:          3,
+		InitialBackoff:      200 * time.Millisecond,
+		MaxBackoff:          5 * time.Second,
+		BreakerThreshold:    5,
+		BreakerCooldown:     30 * time.Second,
+	}
+}
+
+// Client is safe for concurrent use and meant to be shared.
+type Client struct {
+	http   *http.Client
+	config Config
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+// Response is a response whose body has been read completely.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// TLS is the connection state of an HTTPS response, nil otherwise.
+	TLS *tls.ConnectionState
+}
+
+func New(config Config) *Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	if config.TLSConfig != nil {
+		transport.TLSClientConfig = config.TLSConfig
+	}
+	return &Client{
+		http:     &http.Client{Timeout: config.Timeout, Transport: transport},
+		config:   config,
+		breakers: make(map[string]*breaker),
+	}
+}
+
+// Config returns the settings the client was created with.
+func (c *Client) Config() Config {
+	return c.config
+}
+
+// CallOption changes how a single call to Do is made.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	noRetry bool
+}
+
+// NonIdempotent sends a request that must not be repeated, such as one
+// adding a document: a timeout after the server accepted it would otherwise
+// add it twice. It is attempted once.
+func NonIdempotent() CallOption {
+	return func(o *callOptions) {
+		o.noRetry = true
+	}
+}
+
+// Do sends the request built by newRequest and reads the response. Requests
+// failing with a transient network error, 429 or 5xx are retried with
+// backoff unless NonIdempotent is given; newRequest is called for every
+// attempt so each one is signed afresh. The last response is returned even
+// if its status is an error.
+func (c *Client) Do(ctx context.Context, newRequest func(ctx context.Context) (*http.Request, error), opts ...CallOption) (*Response, error) {
+	var options callOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	maxRetries := c.config.MaxRetries
+	if options.noRetry {
+		maxRetries = 0
+	}
+
+	req, err := newRequest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	host := req.URL.Host
+	b := c.breaker(host)
+	if !b.allow() {
+		return nil, fmt.Errorf("%s: %w", host, ErrCircuitOpen)
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if req, err = newRequest(ctx); err != nil {
+				b.abandon()
+				return nil, err
+			}
+		}
+
+		resp, err := c.attempt(req)
+		if err != nil && ctx.Err() != nil {
+			// The caller gave up; that says nothing about the host
+			b.abandon()
+			return nil, err
+		}
+		retryable := err != nil && isTransient(err) || err == nil && retryableStatus(resp.StatusCode)
+		if !retryable || attempt >= maxRetries || ctx.Err() != nil {
+			b.record(err == nil && !retryableStatus(resp.StatusCode))
+			return resp, err
+		}
+
+		wait := c.backoff(attempt, resp)
+		if err != nil {
+			log.Printf("kbhttp: %s %s failed, retrying in %s: %v", req.Method, req.URL.Path, wait, err)
+		} else {
+			log.Printf("kbhttp: %s %s returned %d, retrying in %s", req.Method, req.URL.Path, resp.StatusCode, wait)
+		}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			b.abandon()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (c *Client) attempt(req *http.Request) (*Response, error) {
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body, TLS: resp.TLS}, nil
+}
+
+// backoff doubles InitialBackoff per attempt up to MaxBackoff, with jitter,
+// unless the server asked for a delay with Retry-After.
+func (c *Client) backoff(attempt int, resp *Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, c.config.MaxBackoff)
+		}
+	}
+	wait := c.config.InitialBackoff << attempt
+	if wait <= 0 || wait > c.config.MaxBackoff {
+		wait = c.config.MaxBackoff
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+func (c *Client) breaker(host string) *breaker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &breaker{host: host, threshold: c.config.BreakerThreshold, cooldown: c.config.BreakerCooldown}
+		c.breakers[host] = b
+	}
+	return b
+}
+
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// isTransient reports whether a request error is worth retrying: timeouts,
+// connections refused or reset, and responses cut short. Everything else,
+// such as an unknown host or an untrusted certificate, fails the same way
+// on every attempt.
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,214 @@
+package kbhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://kb.example.com", Err: err}
+	}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "timeout", err: urlErr(&net.OpError{Op: "read", Err: os.ErrDeadlineExceeded}), want: true},
+		{name: "connection refused", err: urlErr(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), want: true},
+		{name: "connection reset", err: urlErr(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), want: true},
+		{name: "unexpected eof", err: urlErr(io.ErrUnexpectedEOF), want: true},
+		{name: "unknown host", err: urlErr(&net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "kb.example.com", IsNotFound: true}}), want: false},
+		{name: "canceled", err: urlErr(context.Canceled), want: false},
+		{name: "plain error", err: urlErr(errors.New("unsupported protocol scheme")), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTransient(tt.err); got != tt.want {
+				t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// statusServer answers with the statuses in turn, repeating the last one,
+// and counts the requests it receives.
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		w.WriteHeader(statuses[min(n, len(statuses))-1])
+		fmt.Fprintf(w, "attempt %d", n)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func testConfig() Config {
+	return Config{
+		Timeout:        time.Second,
+		MaxRetries:     2,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     5 * time.Millisecond,
+	}
+}
+
+func get(url string) func(ctx context.Context) (*http.Request, error) {
+	return func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		opts       []CallOption
+		wantStatus int
+		wantCalls  int32
+	}{
+		{name: "success", statuses: []int{200}, wantStatus: 200, wantCalls: 1},
+		{name: "recovers", statuses: []int{503, 502, 200}, wantStatus: 200, wantCalls: 3},
+		{name: "rate limited", statuses: []int{429, 200}, wantStatus: 200, wantCalls: 2},
+		{name: "gives up", statuses: []int{500}, wantStatus: 500, wantCalls: 3},
+		{name: "client error", statuses: []int{400}, wantStatus: 400, wantCalls: 1},
+		{name: "not found", statuses: []int{404}, wantStatus: 404, wantCalls: 1},
+		{name: "non-idempotent", statuses: []int{503, 200}, opts: []CallOption{NonIdempotent()}, wantStatus: 503, wantCalls: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, calls := statusServer(t, tt.statuses...)
+			resp, err := New(testConfig()).Do(context.Background(), get(srv.URL), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("server received %d requests, want %d", got, tt.wantCalls)
+			}
+			if want := fmt.Sprintf("attempt %d", tt.wantCalls); string(resp.Body) != want {
+				t.Errorf("body = %q, want the last attempt's %q", resp.Body, want)
+			}
+		})
+	}
+}
+
+func TestDoStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	config := testConfig()
+	config.InitialBackoff, config.MaxBackoff = time.Second, time.Second
+	start := time.Now()
+	New(config).Do(ctx, get(srv.URL))
+	if got := calls.Load(); got != 1 {
+		t.Errorf("server received %d requests after the caller gave up, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Do took %s after the caller gave up", elapsed)
+	}
+}
+
+func TestDoBreaker(t *testing.T) {
+	srv, calls := statusServer(t, 500, 500, 200)
+	config := testConfig()
+	config.MaxRetries = 0
+	config.BreakerThreshold = 2
+	config.BreakerCooldown = 50 * time.Millisecond
+	client := New(config)
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Do(context.Background(), get(srv.URL)); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if _, err := client.Do(context.Background(), get(srv.URL)); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error with the breaker open = %v, want ErrCircuitOpen", err)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("server received %d requests, want 2", got)
+	}
+
+	// After the cooldown one probe goes through and closes the breaker
+	time.Sleep(60 * time.Millisecond)
+	resp, err := client.Do(context.Background(), get(srv.URL))
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("probe = %v, %v", resp, err)
+	}
+	if _, err := client.Do(context.Background(), get(srv.URL)); err != nil {
+		t.Errorf("call after the probe: %v", err)
+	}
+}
+
+func TestBreakerSingleProbe(t *testing.T) {
+	b := &breaker{threshold: 1, cooldown: time.Millisecond}
+	b.record(false)
+	if b.allow() {
+		t.Fatal("breaker allowed a call while open")
+	}
+	time.Sleep(2 * time.Millisecond)
+	if !b.allow() {
+		t.Fatal("breaker refused the probe after the cooldown")
+	}
+	if b.allow() {
+		t.Error("breaker allowed a second call while probing")
+	}
+	b.abandon()
+	if !b.allow() {
+		t.Error("breaker refused a probe after the last one was abandoned")
+	}
+	b.record(false)
+	if b.allow() {
+		t.Error("breaker allowed a call after the probe failed")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	client := New(Config{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	tests := []struct {
+		name       string
+		attempt    int
+		retryAfter string
+		low, high  time.Duration
+	}{
+		{name: "first", attempt: 0, low: 50 * time.Millisecond, high: 100 * time.Millisecond},
+		{name: "doubles", attempt: 2, low: 200 * time.Millisecond, high: 400 * time.Millisecond},
+		{name: "capped", attempt: 10, low: 500 * time.Millisecond, high: time.Second},
+		{name: "overflow capped", attempt: 70, low: 500 * time.Millisecond, high: time.Second},
+		{name: "retry-after", attempt: 0, retryAfter: "0", low: 0, high: 0},
+		{name: "retry-after capped", attempt: 0, retryAfter: "30", low: time.Second, high: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp *Response
+			if tt.retryAfter != "" {
+				resp = &Response{Header: http.Header{"Retry-After": {tt.retryAfter}}}
+			}
+			for i := 0; i < 20; i++ {
+				if wait := client.backoff(tt.attempt, resp); wait < tt.low || wait > tt.high {
+					t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempt, wait, tt.low, tt.high)
+				}
+			}
+		})
+	}
+}
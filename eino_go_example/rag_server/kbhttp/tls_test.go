@@ -0,0 +1,143 @@
+package kbhttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCA writes a DER certificate to a PEM file.
+func writeCA(t *testing.T, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// otherCA returns a self-signed CA certificate for 127.0.0.1 that did not
+// sign the httptest servers' certificate.
+func otherCA(t *testing.T) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func TestLoadTLSConfig(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	notPEM := filepath.Join(t.TempDir(), "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		caFile   string
+		insecure bool
+		wantNil  bool
+		wantErr  string
+	}{
+		{name: "system roots", wantNil: true},
+		{name: "ca file", caFile: writeCA(t, srv.Certificate().Raw)},
+		{name: "insecure", insecure: true},
+		{name: "missing ca file", caFile: filepath.Join(t.TempDir(), "missing.pem"), wantErr: "failed to read CA file"},
+		{name: "no certificates", caFile: notPEM, wantErr: "no certificates found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := LoadTLSConfig(tt.caFile, tt.insecure)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadTLSConfig = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (config == nil) != tt.wantNil {
+				t.Fatalf("config = %v, want nil %v", config, tt.wantNil)
+			}
+			if config != nil && (config.MinVersion != tls.VersionTLS12 || config.InsecureSkipVerify != tt.insecure) {
+				t.Errorf("config = %+v", config)
+			}
+		})
+	}
+}
+
+func TestTLSConnections(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		caFile   string
+		insecure bool
+		wantErr  bool
+	}{
+		{name: "custom ca", caFile: writeCA(t, srv.Certificate().Raw)},
+		{name: "insecure", insecure: true},
+		{name: "system roots", wantErr: true},
+		{name: "another ca", caFile: writeCA(t, otherCA(t)), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := LoadTLSConfig(tt.caFile, tt.insecure)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := New(Config{Timeout: time.Second, TLSConfig: tlsConfig})
+			resp, err := client.Do(context.Background(), get(srv.URL))
+			if tt.wantErr {
+				var certErr *tls.CertificateVerificationError
+				if !errors.As(err, &certErr) {
+					t.Errorf("Do = %v, want a certificate verification error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != http.StatusOK || resp.TLS == nil {
+				t.Errorf("response = %d, TLS %v", resp.StatusCode, resp.TLS)
+			}
+		})
+	}
+}
@@ -0,0 +1,397 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
+
+	"rag-backend/chatmodel"
+	"rag-backend/chunking"
+	"rag-backend/jobs"
+	"rag-backend/kbauth"
+	"rag-backend/kbhttp"
+	"rag-backend/memorykb"
+	"rag-backend/rag"
+	"rag-backend/ragkb"
+	"rag-backend/ragkb/ragkbtest"
+	"rag-backend/vikingdb"
+)
+
+func main() {
+	// Load environment variables
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: .env file not found: %v", err)
+	}
+
+	r, backend, err := newServer(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Start server
+	port := getEnvOrDefault("PORT", "8080")
+	log.Printf("Starting RAG server on :%s with %s backend", port, backend.Name())
+	if err := r.Run(":" + port); err != nil {
+		log.Fatal("Failed to start server:", err)
+	}
+}
+
+// newServer builds the backends, chat model and routes from the environment.
+// Jobs run until ctx is done.
+func newServer(ctx context.Context) (*gin.Engine, rag.Backend, error) {
+	// Select the retrieval backend
+	backendName := getEnvOrDefault("RAG_BACKEND", "ragkb")
+	backend, err := newBackend(ctx, backendName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize backend: %w", err)
+	}
+
+	// Further backends that federated queries may name as sources
+	var extraBackends []rag.Backend
+	for _, name := range strings.Split(os.Getenv("RAG_EXTRA_BACKENDS"), ",") {
+		if name = strings.TrimSpace(name); name == "" || name == backendName {
+			continue
+		}
+		extra, err := newBackend(ctx, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize extra backend %s: %w", name, err)
+		}
+		extraBackends = append(extraBackends, extra)
+	}
+
+	// Create the chat model; without one the server runs retrieval-only
+	chatModel, err := chatmodel.New(ctx, chatModelConfig(backendName))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create chat model: %w", err)
+	}
+	if chatModel == nil {
+		log.Printf("No chat model configured, running retrieval-only")
+	}
+
+	// Initialize RAG service
+	ragService := rag.NewService(backend, chatModel, &rag.Config{
+		ConversationTTL:         time.Duration(getEnvAsInt("CONVERSATION_TTL_MINUTES", 30)) * time.Minute,
+		ConversationMaxMessages: getEnvAsInt("CONVERSATION_MAX_MESSAGES", 20),
+		MaxImages:               getEnvAsInt("CHAT_MAX_IMAGES", 0),
+		ExtraBackends:           extraBackends,
+	})
+
+	// Queue uploads for background ingestion
+	if _, ok := backend.(rag.DocumentAdder); ok && getEnvAsBool("JOBS_ENABLED", true) {
+		err := ragService.StartJobs(ctx, jobs.Config{
+			Path:      getEnvOrDefault("JOBS_DB_PATH", "jobs.db"),
+			Workers:   getEnvAsInt("JOBS_WORKERS", 2),
+			Retention: time.Duration(getEnvAsInt("JOBS_RETENTION_HOURS", 168)) * time.Hour,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to start ingestion jobs: %w", err)
+		}
+	}
+
+	r := rag.NewRouter(ragService)
+
+	// Backend specific endpoints
+	if kb, ok := backend.(*ragkb.Backend); ok {
+		r.GET("/api/collections", kb.ListCollectionsHandler)
+	}
+	if collection := getEnvOrDefault("MEMORYKB_COLLECTION", ""); collection != "" && chatModel != nil {
+		memoryChat := memorykb.NewChatService(newMemoryClient(), chatModel, collection, getEnvOrDefault("MEMORYKB_ASSISTANT_ID", "assistant"))
+		r.POST("/memory/chat", memoryChat.ChatHandler)
+	}
+
+	return r, backend, nil
+}
+
+// arkDefaults are the ARK endpoint and chat model used when ARK_BASE_URL and
+// ARK_CHAT_MODEL are unset, by backend. They keep those of the servers the
+// backends came from: ragKB_example for ragkb and memorykb, vectorDB_example
+// for vikingdb.
+var arkDefaults = map[string]struct{ baseURL, model string }{
+	"ragkb":    {"https://ark.cn-beijing.volces.com/api/v3", "ep-20241211105246-lmqdx"},
+	"memorykb": {"https://ark.cn-beijing.volces.com/api/v3", "ep-20241211105246-lmqdx"},
+	"vikingdb": {"https://ark.ap-southeast.bytepluses.com/api/v3", "seed-1-6-250615"},
+}
+
+// chatModelConfig reads CHAT_PROVIDER and the provider's settings. The
+// provider defaults to ark when ARK_API_KEY is set and none otherwise; ARK
+// defaults depend on the backend.
+func chatModelConfig(backendName string) *chatmodel.Config {
+	provider := chatmodel.ProviderNone
+	if os.Getenv("ARK_API_KEY") != "" {
+		provider = chatmodel.ProviderARK
+	}
+	provider = getEnvOrDefault("CHAT_PROVIDER", provider)
+
+	switch provider {
+	case chatmodel.ProviderARK:
+		defaults, ok := arkDefaults[backendName]
+		if !ok {
+			defaults = arkDefaults["ragkb"]
+		}
+		return &chatmodel.Config{
+			Provider: provider,
+			APIKey:   os.Getenv("ARK_API_KEY"),
+			BaseURL:  getEnvOrDefault("ARK_BASE_URL", defaults.baseURL),
+			Model:    getEnvOrDefault("ARK_CHAT_MODEL", getEnvOrDefault("CHAT_MODEL", defaults.model)),
+		}
+	case chatmodel.ProviderOpenAI:
+		return &chatmodel.Config{
+			Provider: provider,
+			APIKey:   os.Getenv("OPENAI_API_KEY"),
+			BaseURL:  getEnvOrDefault("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+			Model:    getEnvOrDefault("OPENAI_MODEL", os.Getenv("CHAT_MODEL")),
+		}
+	default:
+		return &chatmodel.Config{
+			Provider: provider,
+			Template: os.Getenv("ECHO_TEMPLATE"),
+		}
+	}
+}
+
+// newBackend builds the backend named by RAG_BACKEND from its environment variables.
+func newBackend(ctx context.Context, name string) (rag.Backend, error) {
+	switch name {
+	case "ragkb":
+		// ragKB Configuration
+		config := &ragkb.Config{
+			Domain:         getEnvOrDefault("RAGKB_DOMAIN", "api-knowledgebase.mlp.cn-hongkong.bytepluses.com"),
+			AccountID:      getEnvOrDefault("RAGKB_ACCOUNT_ID", "3000822947"),
+			AccessKey:      getEnvOrDefault("RAGKB_ACCESS_KEY", ""),
+			SecretKey:      getEnvOrDefault("RAGKB_SECRET_KEY", ""),
+			Region:         getEnvOrDefault("RAGKB_REGION", "cn-hongkong"),
+			ProjectName:    getEnvOrDefault("RAGKB_PROJECT", "default"),
+			CollectionName: getEnvOrDefault("RAGKB_COLLECTION", "test"),
+			Scheme:         getEnvOrDefault("RAGKB_SCHEME", "https"),
+			Port:           getEnvAsInt("RAGKB_PORT", 0),
+			CAFile:         os.Getenv("RAGKB_CA_FILE"),
+			// For testing against endpoints with self-signed certificates only
+			InsecureSkipVerify: getEnvAsBool("RAGKB_TLS_INSECURE_SKIP_VERIFY", false),
+			// Search defaults
+			SearchLimit:         getEnvAsInt("RAGKB_SEARCH_LIMIT", 10),
+			DenseWeight:         getEnvAsFloat("RAGKB_DENSE_WEIGHT", 0.5),
+			RerankSwitch:        getEnvAsBool("RAGKB_RERANK", false),
+			RerankOnlyChunk:     getEnvAsBool("RAGKB_RERANK_ONLY_CHUNK", false),
+			ChunkGroup:          getEnvAsBool("RAGKB_CHUNK_GROUP", true),
+			ChunkDiffusionCount: getEnvAsInt("RAGKB_CHUNK_DIFFUSION_COUNT", 0),
+			Rewrite:             getEnvAsBool("RAGKB_REWRITE", false),
+			CollectionCacheTTL:  time.Duration(getEnvAsInt("RAGKB_COLLECTION_CACHE_MINUTES", 5)) * time.Minute,
+			HTTPClient:          kbHTTPClient(),
+		}
+		var kb *ragkb.Backend
+		var err error
+		if corpus := getEnvOrDefault("RAGKB_MOCK_CORPUS", ""); corpus != "" {
+			kb, err = newMockRagKB(config, corpus)
+		} else if config.AccessKey == "" || config.SecretKey == "" {
+			return nil, fmt.Errorf("RAGKB_ACCESS_KEY and RAGKB_SECRET_KEY are required")
+		} else {
+			kb, err = ragkb.New(config)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRagKB(ctx, kb); err != nil {
+			return nil, err
+		}
+		return kb, nil
+
+	case "vikingdb":
+		config := &vikingdb.Config{
+			Host:           getEnvOrDefault("VIKINGDB_HOST", "vikingdb.volces.com"),
+			Region:         getEnvOrDefault("VIKINGDB_REGION", "cn-beijing"),
+			AK:             os.Getenv("VIKINGDB_AK"),
+			SK:             os.Getenv("VIKINGDB_SK"),
+			CollectionName: getEnvOrDefault("VIKINGDB_COLLECTION", "rag_collection"),
+			IndexName:      getEnvOrDefault("VIKINGDB_INDEX", "rag_index"),
+			Partition:      os.Getenv("VIKINGDB_PARTITION"),
+			ModelName:      getEnvOrDefault("VIKINGDB_MODEL", "bge-m3"),
+			TopK:           getEnvAsInt("VIKINGDB_TOP_K", 5),
+			ScoreThreshold: getEnvAsFloat("VIKINGDB_SCORE_THRESHOLD", 0.7),
+		}
+		if config.AK == "" || config.SK == "" {
+			return nil, fmt.Errorf("VIKINGDB_AK and VIKINGDB_SK are required")
+		}
+		chunkingConfig, err := chunkingConfig(config.CollectionName)
+		if err != nil {
+			return nil, err
+		}
+		config.Chunking = chunkingConfig
+		return vikingdb.New(ctx, config)
+
+	case "memorykb":
+		collection := getEnvOrDefault("MEMORYKB_COLLECTION", "")
+		if collection == "" {
+			return nil, fmt.Errorf("MEMORYKB_COLLECTION is required")
+		}
+		var memoryTypes []string
+		if types := getEnvOrDefault("MEMORYKB_MEMORY_TYPES", ""); types != "" {
+			memoryTypes = strings.Split(types, ",")
+		}
+		return memorykb.NewBackend(newMemoryClient(), collection, getEnvAsInt("MEMORYKB_SEARCH_LIMIT", 5), memoryTypes), nil
+
+	default:
+		return nil, fmt.Errorf("unknown RAG_BACKEND %q, expected ragkb, vikingdb or memorykb", name)
+	}
+}
+
+// newMockRagKB serves the corpus at path from an in-process fake ragKB so the
+// server runs offline. The fake lives as long as the process.
+func newMockRagKB(config *ragkb.Config, path string) (*ragkb.Backend, error) {
+	chunks, err := ragkbtest.LoadCorpus(path)
+	if err != nil {
+		return nil, err
+	}
+
+	signer := &kbauth.Signer{
+		AccessKey: getEnvOrDefault("RAGKB_ACCESS_KEY", "mock-ak"),
+		SecretKey: getEnvOrDefault("RAGKB_SECRET_KEY", "mock-sk"),
+		Region:    config.Region,
+		AccountID: config.AccountID,
+	}
+	server := ragkbtest.NewServer(signer, config.CollectionName)
+	server.SetCorpus(chunks)
+
+	// Subdirectories of a corpus directory become collections of their own
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		collections, err := ragkbtest.LoadCollections(path)
+		if err != nil {
+			return nil, err
+		}
+		for name, chunks := range collections {
+			server.SetCollection(name, chunks)
+			log.Printf("Serving %d chunks from %s/%s as collection %s", len(chunks), path, name, name)
+		}
+	}
+
+	// The fake serves plain HTTP on a port of its own
+	config.Domain = server.Domain()
+	config.Scheme = "http"
+	config.Port = 0
+	config.AccessKey = signer.AccessKey
+	config.SecretKey = signer.SecretKey
+	log.Printf("Serving %d chunks from %s with a mock ragKB at %s", len(chunks), path, server.URL)
+	return ragkb.New(config)
+}
+
+// checkRagKB runs the startup connectivity check selected by
+// RAGKB_STARTUP_CHECK: "warn" (default) logs failures, "strict" refuses to
+// start and "off" skips the check.
+func checkRagKB(ctx context.Context, kb *ragkb.Backend) error {
+	mode := getEnvOrDefault("RAGKB_STARTUP_CHECK", "warn")
+	switch mode {
+	case "off":
+		return nil
+	case "warn", "strict":
+	default:
+		return fmt.Errorf("unknown RAGKB_STARTUP_CHECK %q, expected warn, strict or off", mode)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(getEnvAsInt("RAGKB_STARTUP_CHECK_TIMEOUT_SECONDS", 10))*time.Second)
+	defer cancel()
+	report, err := kb.CheckConnectivity(ctx)
+	log.Printf("ragKB connectivity: %s", report)
+	if err != nil {
+		if mode == "strict" {
+			return fmt.Errorf("ragKB connectivity check failed: %w", err)
+		}
+		log.Printf("Warning: ragKB connectivity check failed: %v", err)
+	}
+	return nil
+}
+
+// newMemoryClient uses the ragKB credentials unless memoryKB ones are set.
+func newMemoryClient() *memorykb.Client {
+	client := memorykb.NewClient(getEnvOrDefault("MEMORYKB_DOMAIN", "api-knowledgebase.mlp.cn-beijing.volces.com"), &kbauth.Signer{
+		AccessKey: getEnvOrDefault("MEMORYKB_ACCESS_KEY", os.Getenv("RAGKB_ACCESS_KEY")),
+		SecretKey: getEnvOrDefault("MEMORYKB_SECRET_KEY", os.Getenv("RAGKB_SECRET_KEY")),
+		Region:    getEnvOrDefault("MEMORYKB_REGION", "cn-north-1"),
+	})
+	client.HTTPClient = kbHTTPClient()
+	return client
+}
+
+// kbHTTPClient is shared by the knowledge base clients so they pool
+// connections and see the same circuit breaker for each host.
+var kbHTTPClient = sync.OnceValue(func() *kbhttp.Client {
+	defaults := kbhttp.DefaultConfig()
+	return kbhttp.New(kbhttp.Config{
+		Timeout:             time.Duration(getEnvAsInt("KB_HTTP_TIMEOUT_SECONDS", int(defaults.Timeout/time.Second))) * time.Second,
+		MaxIdleConnsPerHost: getEnvAsInt("KB_HTTP_MAX_IDLE_CONNS_PER_HOST", defaults.MaxIdleConnsPerHost),
+		MaxRetries:          getEnvAsInt("KB_HTTP_MAX_RETRIES", defaults.MaxRetries),
+		InitialBackoff:      time.Duration(getEnvAsInt("KB_HTTP_BACKOFF_MS", int(defaults.InitialBackoff/time.Millisecond))) * time.Millisecond,
+		MaxBackoff:          time.Duration(getEnvAsInt("KB_HTTP_MAX_BACKOFF_MS", int(defaults.MaxBackoff/time.Millisecond))) * time.Millisecond,
+		BreakerThreshold:    getEnvAsInt("KB_BREAKER_THRESHOLD", defaults.BreakerThreshold),
+		BreakerCooldown:     time.Duration(getEnvAsInt("KB_BREAKER_COOLDOWN_SECONDS", int(defaults.BreakerCooldown/time.Second))) * time.Second,
+	})
+})
+
+func getEnvOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// chunkingConfig returns the chunking settings for a collection: its entry
+// in CHUNKING_CONFIG_FILE, overridden by the VIKINGDB_CHUNK_* variables.
+func chunkingConfig(collection string) (chunking.Config, error) {
+	var config chunking.Config
+	if path := os.Getenv("CHUNKING_CONFIG_FILE"); path != "" {
+		configs, err := chunking.LoadFile(path)
+		if err != nil {
+			return config, err
+		}
+		config = configs[collection]
+	}
+
+	override := chunking.Config{
+		Strategy:  os.Getenv("VIKINGDB_CHUNK_STRATEGY"),
+		ChunkSize: getEnvAsInt("VIKINGDB_CHUNK_SIZE", 0),
+	}
+	if os.Getenv("VIKINGDB_CHUNK_OVERLAP") != "" {
+		overlap := getEnvAsInt("VIKINGDB_CHUNK_OVERLAP", 0)
+		override.Overlap = &overlap
+	}
+	config = config.Merge(override)
+	if err := config.Validate(); err != nil {
+		return config, fmt.Errorf("collection %s: %w", collection, err)
+	}
+	resolved := config.WithDefaults()
+	log.Printf("Chunking %s: strategy %s, size %d, overlap %d", collection, resolved.Strategy, resolved.ChunkSize, *resolved.Overlap)
+	return config, nil
+}
+
+func getEnvAsInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/chatmodel"
+)
+
+// clearChatEnv unsets the chat model variables for the test.
+func clearChatEnv(t *testing.T) {
+	for _, key := range []string{"CHAT_PROVIDER", "ARK_API_KEY", "ARK_BASE_URL", "ARK_CHAT_MODEL", "CHAT_MODEL", "OPENAI_API_KEY", "OPENAI_BASE_URL", "OPENAI_MODEL", "ECHO_TEMPLATE"} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestChatModelConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend string
+		env     map[string]string
+		want    chatmodel.Config
+	}{
+		{name: "no credentials", backend: "ragkb", want: chatmodel.Config{Provider: chatmodel.ProviderNone}},
+		{
+			name:    "ark for ragkb",
+			backend: "ragkb",
+			env:     map[string]string{"ARK_API_KEY": "key"},
+			want:    chatmodel.Config{Provider: chatmodel.ProviderARK, APIKey: "key", BaseURL: "https://ark.cn-beijing.volces.com/api/v3", Model: "ep-20241211105246-lmqdx"},
+		},
+		{
+			name:    "ark for vikingdb",
+			backend: "vikingdb",
+			env:     map[string]string{"ARK_API_KEY": "key"},
+			want:    chatmodel.Config{Provider: chatmodel.ProviderARK, APIKey: "key", BaseURL: "https://ark.ap-southeast.bytepluses.com/api/v3", Model: "seed-1-6-250615"},
+		},
+		{
+			name:    "ark settings",
+			backend: "vikingdb",
+			env:     map[string]string{"ARK_API_KEY": "key", "ARK_BASE_URL": "https://ark.example.com", "CHAT_MODEL": "ep-1"},
+			want:    chatmodel.Config{Provider: chatmodel.ProviderARK, APIKey: "key", BaseURL: "https://ark.example.com", Model: "ep-1"},
+		},
+		{
+			name:    "openai",
+			backend: "ragkb",
+			env:     map[string]string{"CHAT_PROVIDER": "openai", "OPENAI_API_KEY": "key", "OPENAI_MODEL": "gpt-4o-mini"},
+			want:    chatmodel.Config{Provider: chatmodel.ProviderOpenAI, APIKey: "key", BaseURL: "https://api.openai.com/v1", Model: "gpt-4o-mini"},
+		},
+		{
+			name:    "echo",
+			backend: "ragkb",
+			env:     map[string]string{"CHAT_PROVIDER": "echo", "ECHO_TEMPLATE": "{{.System}}"},
+			want:    chatmodel.Config{Provider: chatmodel.ProviderEcho, Template: "{{.System}}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearChatEnv(t)
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if got := chatModelConfig(tt.backend); *got != tt.want {
+				t.Errorf("config = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRetrievalOnlyServer starts the server on a mock corpus with no chat
+// model credentials at all.
+func TestRetrievalOnlyServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clearChatEnv(t)
+	corpus := t.TempDir()
+	if err := os.WriteFile(filepath.Join(corpus, "channels.md"), []byte("Channels connect goroutines."), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("RAG_BACKEND", "ragkb")
+	t.Setenv("RAG_EXTRA_BACKENDS", "")
+	t.Setenv("RAGKB_MOCK_CORPUS", corpus)
+	t.Setenv("MEMORYKB_COLLECTION", "chat_memory")
+	t.Setenv("JOBS_ENABLED", "false")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	router, backend, err := newServer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backend.Name() == "" {
+		t.Error("backend has no name")
+	}
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("/query", `{"query": "channels"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("query status = %d, body %s", w.Code, w.Body)
+	}
+	var response map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.Body.String(), "Channels connect goroutines.") {
+		t.Errorf("query response = %s, want the corpus chunk", w.Body)
+	}
+	if w := post("/query?rag=true", `{"query": "channels"}`); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("rag=true status = %d, want 503 without a chat model", w.Code)
+	}
+	if w := post("/memory/chat", `{"user_id": "1234", "message": "Hi"}`); w.Code != http.StatusNotFound {
+		t.Errorf("memory chat status = %d, want it not registered", w.Code)
+	}
+}
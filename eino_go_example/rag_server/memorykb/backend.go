@@ -0,0 +1,83 @@
+package memorykb
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/cloudwego/eino/schema"
+
+	"rag-backend/rag"
+)
+
+// Backend exposes a memory collection as a rag.Backend. Each memory becomes
+// one document, so RAG answers can draw on what is known about the user.
+type Backend struct {
+	client      *Client
+	collection  string
+	limit       int
+	memoryTypes []string
+}
+
+// NewBackend searches collection for up to limit memories of memoryTypes,
+// the user's profile when none are given.
+func NewBackend(client *Client, collection string, limit int, memoryTypes []string) *Backend {
+	if len(memoryTypes) == 0 {
+		memoryTypes = []string{EntityTypeSysProfile}
+	}
+	return &Backend{
+		client:      client,
+		collection:  collection,
+		limit:       limit,
+		memoryTypes: memoryTypes,
+	}
+}
+
+func (b *Backend) Name() string {
+	return "memorykb"
+}
+
+// Search returns the user's memories most relevant to query. Memories are
+// per user, so opts.UserID is required.
+func (b *Backend) Search(ctx context.Context, query string, opts *rag.SearchOptions) ([]*schema.Document, error) {
+	if opts == nil || opts.UserID == "" {
+		return nil, fmt.Errorf("%w: user_id is required to search memories", rag.ErrMissingOption)
+	}
+	if !opts.Filter.IsEmpty() || opts.Partition != "" || opts.Project != "" || len(opts.CollectionNames()) > 0 {
+		return nil, fmt.Errorf("filter, partition, project and collections: %w by memorykb", rag.ErrUnsupportedOption)
+	}
+	limit := b.limit
+	if opts.TopK != nil {
+		limit = *opts.TopK
+	}
+
+	result, err := b.client.Search(ctx, &SearchRequest{
+		CollectionName: b.collection,
+		Query:          query,
+		Limit:          limit,
+		Filter: SearchFilter{
+			UserID:     opts.UserID,
+			MemoryType: b.memoryTypes,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]*schema.Document, len(result.ResultList))
+	for i := range result.ResultList {
+		memory := &result.ResultList[i]
+		docs[i] = (&schema.Document{
+			ID:      memory.ID,
+			Content: memory.InfoText(),
+			MetaData: map[string]interface{}{
+				"memory_type": memory.MemoryType,
+				"session_id":  memory.SessionID,
+				"time":        memory.Time,
+			},
+		}).WithScore(memory.Score)
+	}
+
+	log.Printf("memoryKB search success, query=%v, found %d memories", query, len(docs))
+	return docs, nil
+}
@@ -0,0 +1,184 @@
+package memorykb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/rag"
+)
+
+const memorySystemPrompt = "You are an AI assistant with excellent memory who can remember historical conversations with users.\n" +
+	"Please refer to the background information below and naturally continue the conversation in a conversational manner, " +
+	"as if you really remember what happened before.\n\n" +
+	"[Background Information]\n%s"
+
+// writeBackTimeout bounds recording a turn in memoryKB.
+const writeBackTimeout = 30 * time.Second
+
+// ChatService answers chat messages with the user's memories as background
+// information and records every turn back into the collection.
+type ChatService struct {
+	client      *Client
+	chatModel   model.BaseChatModel
+	collection  string
+	assistantID string
+
+	writeBackTimeout time.Duration
+	// writeBacks tracks turns still being recorded
+	writeBacks sync.WaitGroup
+}
+
+func NewChatService(client *Client, chatModel model.BaseChatModel, collection, assistantID string) *ChatService {
+	return &ChatService{
+		client:           client,
+		chatModel:        chatModel,
+		collection:       collection,
+		assistantID:      assistantID,
+		writeBackTimeout: writeBackTimeout,
+	}
+}
+
+// Wait blocks until every turn being recorded in memoryKB has been written
+// or has failed, which takes at most the write-back timeout.
+func (s *ChatService) Wait() {
+	s.writeBacks.Wait()
+}
+
+type ChatRequest struct {
+	UserID      string `json:"user_id" binding:"required"`
+	AssistantID string `json:"assistant_id,omitempty"`
+	SessionID   string `json:"session_id,omitempty"`
+	Message     string `json:"message" binding:"required"`
+	// History holds earlier turns of this session, oldest first.
+	History     []rag.Message `json:"history,omitempty"`
+	MemoryTypes []string      `json:"memory_types,omitempty"`
+	Limit       int           `json:"limit,omitempty"`
+}
+
+type ChatResponse struct {
+	Answer    string   `json:"answer"`
+	SessionID string   `json:"session_id"`
+	Memories  []Memory `json:"memories"`
+}
+
+// Chat looks up the user's memories, answers with them as background
+// information, and records the new turn so future memories reflect it.
+func (s *ChatService) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	memoryTypes := req.MemoryTypes
+	if len(memoryTypes) == 0 {
+		memoryTypes = []string{EntityTypeSysProfile}
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = 5
+	}
+	assistantID := req.AssistantID
+	if assistantID == "" {
+		assistantID = s.assistantID
+	}
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = fmt.Sprintf("session_%s_%d", req.UserID, Timestamp(time.Now()))
+	}
+
+	// Step 1: query the user's memories
+	result, err := s.client.Search(ctx, &SearchRequest{
+		CollectionName: s.collection,
+		Query:          req.Message,
+		Limit:          limit,
+		Filter: SearchFilter{
+			UserID:     req.UserID,
+			MemoryType: memoryTypes,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("memory search failed: %w", err)
+	}
+	log.Printf("memoryKB search success, user=%s, found %d memories", req.UserID, len(result.ResultList))
+
+	// Step 2: use the memories as background information
+	memoryTexts := make([]string, 0, len(result.ResultList))
+	for i := range result.ResultList {
+		memoryTexts = append(memoryTexts, result.ResultList[i].InfoText())
+	}
+	messages := []*schema.Message{
+		schema.SystemMessage(fmt.Sprintf(memorySystemPrompt, strings.Join(memoryTexts, "\n"))),
+	}
+	messages = append(messages, rag.HistoryMessages(req.History)...)
+	messages = append(messages, schema.UserMessage(req.Message))
+
+	// Step 3: generate the answer
+	response, err := s.chatModel.Generate(ctx, messages)
+	if err != nil {
+		return nil, fmt.Errorf("chat model generation failed: %w", err)
+	}
+
+	// Step 4: write the turn back; memory extraction is asynchronous upstream
+	// anyway, so do not make the caller wait for it.
+	s.writeBack(&AddMessagesRequest{
+		CollectionName: s.collection,
+		SessionID:      sessionID,
+		Messages: []Message{
+			{Role: "user", Content: req.Message},
+			{Role: "assistant", Content: response.Content},
+		},
+		Metadata: MessagesMetadata{
+			DefaultUserID:      req.UserID,
+			DefaultAssistantID: assistantID,
+			Time:               Timestamp(time.Now()),
+		},
+	})
+
+	return &ChatResponse{
+		Answer:    response.Content,
+		SessionID: sessionID,
+		Memories:  result.ResultList,
+	}, nil
+}
+
+// writeBack records a turn in the background, logging the outcome since
+// the caller has already been answered.
+func (s *ChatService) writeBack(req *AddMessagesRequest) {
+	s.writeBacks.Add(1)
+	go func() {
+		defer s.writeBacks.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), s.writeBackTimeout)
+		defer cancel()
+		if err := s.client.AddMessages(ctx, req); err != nil {
+			log.Printf("Failed to add messages to memoryKB, user=%s, session=%s: %v", req.Metadata.DefaultUserID, req.SessionID, err)
+			return
+		}
+		log.Printf("memoryKB messages added, user=%s, session=%s", req.Metadata.DefaultUserID, req.SessionID)
+	}()
+}
+
+// ChatHandler handles memory-augmented chat requests.
+func (s *ChatService) ChatHandler(c *gin.Context) {
+	var req ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := rag.ValidateMessages(req.History); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := s.Chat(c.Request.Context(), &req)
+	if err != nil {
+		log.Printf("Memory chat failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process memory chat"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
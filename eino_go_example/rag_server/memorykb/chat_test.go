@@ -0,0 +1,176 @@
+package memorykb
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/chatmodel"
+)
+
+// profileResponse is a memory search finding the user's profile.
+const profileResponse = `{"code": 0, "data": {"count": 1, "result_list": [
+	{"id": "m1", "memory_type": "sys_profile_v1", "memory_info": {"hobby": "tennis"}, "score": 0.9}]}}`
+
+// newTestChat returns a router serving memory chat with an echo model that
+// answers with its system prompt and the user's message, and the fake
+// memoryKB behind it.
+func newTestChat(t *testing.T) (*gin.Engine, *ChatService, *fakeMemoryKB) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	client, fake := newTestClient(t, testSigner)
+	fake.responses[searchPath] = profileResponse
+	echo, err := chatmodel.NewEchoModel("{{.System}}\nYou said: {{.Input}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := NewChatService(client, echo, "chat_memory", "assistant")
+	t.Cleanup(service.Wait)
+
+	router := gin.New()
+	router.POST("/memory/chat", service.ChatHandler)
+	return router, service, fake
+}
+
+func postChat(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/memory/chat", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// captureLog collects the standard logger's output until the test ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(previous) })
+	return &buf
+}
+
+func TestChatHandler(t *testing.T) {
+	router, service, fake := newTestChat(t)
+
+	w := postChat(router, `{"user_id": "1234", "session_id": "session-1", "message": "What should I play?",
+		"history": [{"role": "user", "content": "Hi"}, {"role": "assistant", "content": "Hello!"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var response ChatResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(response.Answer, "[Background Information]\nhobby: tennis") {
+		t.Errorf("answer = %q, want the profile in the system prompt", response.Answer)
+	}
+	if !strings.HasSuffix(response.Answer, "You said: What should I play?") {
+		t.Errorf("answer = %q, want the model to answer the message", response.Answer)
+	}
+	if response.SessionID != "session-1" || len(response.Memories) != 1 {
+		t.Errorf("response = %+v", response)
+	}
+
+	service.Wait()
+	requests := fake.Received()
+	if len(requests) != 2 || requests[0].Path != searchPath || requests[1].Path != addMessagesPath {
+		t.Fatalf("server received %+v, want a search then the write-back", requests)
+	}
+	assertJSON(t, requests[0].Body, `{"collection_name": "chat_memory", "query": "What should I play?", "limit": 5,
+		"filter": {"user_id": "1234", "memory_type": ["sys_profile_v1"]}}`)
+
+	var added AddMessagesRequest
+	if err := json.Unmarshal(requests[1].Body, &added); err != nil {
+		t.Fatal(err)
+	}
+	if added.CollectionName != "chat_memory" || added.SessionID != "session-1" ||
+		added.Metadata.DefaultUserID != "1234" || added.Metadata.DefaultAssistantID != "assistant" || added.Metadata.Time == 0 {
+		t.Errorf("write-back = %+v", added)
+	}
+	want := []Message{{Role: "user", Content: "What should I play?"}, {Role: "assistant", Content: response.Answer}}
+	if len(added.Messages) != 2 || added.Messages[0] != want[0] || added.Messages[1] != want[1] {
+		t.Errorf("written messages = %+v, want the new turn %+v", added.Messages, want)
+	}
+}
+
+func TestChatHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		fail bool
+		want int
+	}{
+		{name: "no user", body: `{"message": "Hi"}`, want: http.StatusBadRequest},
+		{name: "invalid history", body: `{"user_id": "1234", "message": "Hi", "history": [{"role": "system", "content": "x"}]}`, want: http.StatusBadRequest},
+		{name: "search fails", body: `{"user_id": "1234", "message": "Hi"}`, fail: true, want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, service, fake := newTestChat(t)
+			if tt.fail {
+				fake.responses[searchPath] = `{"code": 1000003, "message": "collection not found"}`
+			}
+			w := postChat(router, tt.body)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.want, w.Body)
+			}
+			service.Wait()
+			for _, req := range fake.Received() {
+				if req.Path == addMessagesPath {
+					t.Error("a failed chat was written back")
+				}
+			}
+		})
+	}
+}
+
+func TestChatWriteBackFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(service *ChatService, fake *fakeMemoryKB)
+		wantLog string
+	}{
+		{
+			name: "error code",
+			setup: func(_ *ChatService, fake *fakeMemoryKB) {
+				fake.responses[addMessagesPath] = `{"code": 1000001, "message": "quota exceeded"}`
+			},
+			wantLog: "code 1000001, message: quota exceeded",
+		},
+		{
+			name: "timeout",
+			setup: func(service *ChatService, fake *fakeMemoryKB) {
+				service.writeBackTimeout = 50 * time.Millisecond
+				fake.delays[addMessagesPath] = time.Minute
+			},
+			wantLog: "context deadline exceeded",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, service, fake := newTestChat(t)
+			tt.setup(service, fake)
+			logs := captureLog(t)
+
+			start := time.Now()
+			w := postChat(router, `{"user_id": "1234", "session_id": "session-1", "message": "Hi"}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d, want the answer despite the write-back failing", w.Code)
+			}
+			service.Wait()
+			if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+				t.Errorf("write-back took %v, want it bounded by the write-back timeout", elapsed)
+			}
+			if got := logs.String(); !strings.Contains(got, "Failed to add messages to memoryKB, user=1234, session=session-1") || !strings.Contains(got, tt.wantLog) {
+				t.Errorf("log = %q, want the failure with %q", got, tt.wantLog)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+// Package memorykb is a client for the memory knowledge base APIs: memory
+// collection management, conversation ingestion and memory search. It also
+// provides a rag.Backend over memory search and the memory chat service.
+package memorykb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"rag-backend/kbauth"
+	"rag-backend/kbhttp"
+)
+
+// memoryKB endpoints
+const (
+	createCollectionPath = "/api/memory/collection/create"
+	deleteCollectionPath = "/api/memory/collection/delete"
+	addMessagesPath      = "/api/memory/messages/add"
+	searchPath           = "/api/memory/search"
+)
+
+// Builtin event and entity types that can be enabled on a collection.
+const (
+	EventTypeSysEvent          = "sys_event_v1"
+	EventTypeSysProfileCollect = "sys_profile_collect_v1"
+	EntityTypeSysProfile       = "sys_profile_v1"
+)
+
+// Client calls the memoryKB APIs, signing every request, and every retry of
+// it, with Signer.
+type Client struct {
+	Domain     string
+	Scheme     string
+	Signer     *kbauth.Signer
+	HTTPClient *kbhttp.Client
+}
+
+// NewClient returns a client for domain that talks HTTPS with the default
+// kbhttp timeouts, retries and circuit breaker.
+func NewClient(domain string, signer *kbauth.Signer) *Client {
+	return &Client{
+		Domain:     domain,
+		Scheme:     "https",
+		Signer:     signer,
+		HTTPClient: kbhttp.New(kbhttp.DefaultConfig()),
+	}
+}
+
+// CreateCollection creates a memory collection and returns its resource ID.
+func (c *Client) CreateCollection(ctx context.Context, req *CreateCollectionRequest) (string, error) {
+	respBody, err := c.post(ctx, createCollectionPath, req, kbhttp.NonIdempotent())
+	if err != nil {
+		return "", err
+	}
+
+	// collection/create answers in the OpenAPI envelope rather than {code, data}
+	var resp struct {
+		ResponseMetadata struct {
+			RequestID string `json:"RequestId"`
+			Error     *struct {
+				Code    string `json:"Code"`
+				Message string `json:"Message"`
+			} `json:"Error,omitempty"`
+		} `json:"ResponseMetadata"`
+		Result struct {
+			ResourceID string `json:"resource_id"`
+		} `json:"Result"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if apiErr := resp.ResponseMetadata.Error; apiErr != nil {
+		return "", fmt.Errorf("memoryKB API %s error: %s: %s", createCollectionPath, apiErr.Code, apiErr.Message)
+	}
+	return resp.Result.ResourceID, nil
+}
+
+// DeleteCollection deletes a memory collection and all memories in it.
+func (c *Client) DeleteCollection(ctx context.Context, collectionName string) error {
+	return c.call(ctx, deleteCollectionPath, map[string]string{"collection_name": collectionName}, nil)
+}
+
+// AddMessages ingests a conversation session; memories are extracted from it
+// asynchronously according to the collection's event and entity types.
+func (c *Client) AddMessages(ctx context.Context, req *AddMessagesRequest) error {
+	if len(req.Messages) == 0 {
+		return fmt.Errorf("at least one message is required")
+	}
+	// A retry could ingest the session twice
+	return c.call(ctx, addMessagesPath, req, nil, kbhttp.NonIdempotent())
+}
+
+// Search returns the memories most relevant to req.Query.
+func (c *Client) Search(ctx context.Context, req *SearchRequest) (*SearchResult, error) {
+	var result SearchResult
+	if err := c.call(ctx, searchPath, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// call posts payload and decodes the data field of the {code, message, data}
+// envelope into out.
+func (c *Client) call(ctx context.Context, path string, payload, out interface{}, opts ...kbhttp.CallOption) error {
+	respBody, err := c.post(ctx, path, payload, opts...)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Code      int             `json:"code"`
+		Message   string          `json:"message"`
+		RequestID string          `json:"request_id"`
+		Data      json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if resp.Code != 0 {
+		return fmt.Errorf("memoryKB API %s error: code %d, message: %s", path, resp.Code, resp.Message)
+	}
+
+	if out != nil && len(resp.Data) > 0 {
+		if err := json.Unmarshal(resp.Data, out); err != nil {
+			return fmt.Errorf("failed to unmarshal response data: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) post(ctx context.Context, path string, payload interface{}, opts ...kbhttp.CallOption) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := c.Scheme + "://" + c.Domain + path
+	resp, err := c.HTTPClient.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", "application/json")
+
+		if err := c.Signer.Sign(req, body); err != nil {
+			return nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+		return req, nil
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	respBody := resp.Body
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("memoryKB API %s returned status %d: %s", path, resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// Timestamp converts t to the millisecond timestamps used by memoryKB.
+func Timestamp(t time.Time) int64 {
+	return t.UnixMilli()
+}
@@ -0,0 +1,308 @@
+package memorykb
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rag-backend/kbauth"
+	"rag-backend/kbhttp"
+)
+
+var testSigner = &kbauth.Signer{AccessKey: "ak", SecretKey: "sk", Region: "cn-beijing", AccountID: "2100000000"}
+
+// received is a request the fake memoryKB accepted.
+type received struct {
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// fakeMemoryKB verifies every request's signature and answers each path
+// with a canned status and body, by default an empty success envelope.
+// Requests to a path in delays are answered after that long.
+type fakeMemoryKB struct {
+	mu           sync.Mutex
+	requests     []received
+	responses    map[string]string
+	statuses     map[string]int
+	delays       map[string]time.Duration
+	authFailures int
+}
+
+func (f *fakeMemoryKB) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	delay := f.delays[r.URL.Path]
+	f.mu.Unlock()
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := testSigner.Verify(r, body); err != nil {
+		f.authFailures++
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"code": 401, "message": "signature verification failed"}`)
+		return
+	}
+	f.requests = append(f.requests, received{Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+
+	if status, ok := f.statuses[r.URL.Path]; ok {
+		w.WriteHeader(status)
+	}
+	response, ok := f.responses[r.URL.Path]
+	if !ok {
+		response = `{"code": 0, "message": "success"}`
+	}
+	io.WriteString(w, response)
+}
+
+// Received returns the requests accepted so far.
+func (f *fakeMemoryKB) Received() []received {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]received(nil), f.requests...)
+}
+
+// newTestClient returns a client for a fake memoryKB, signing with signer,
+// that does not retry.
+func newTestClient(t *testing.T, signer *kbauth.Signer) (*Client, *fakeMemoryKB) {
+	t.Helper()
+	fake := &fakeMemoryKB{responses: map[string]string{}, statuses: map[string]int{}, delays: map[string]time.Duration{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	host, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Client{
+		Domain:     host.Host,
+		Scheme:     "http",
+		Signer:     signer,
+		HTTPClient: kbhttp.New(kbhttp.Config{Timeout: time.Second}),
+	}, fake
+}
+
+// assertJSON fails unless got and want hold the same JSON value.
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("invalid JSON %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
+
+func TestClientRequests(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		path     string
+		response string
+		call     func(t *testing.T, c *Client)
+		wantBody string
+	}{
+		{
+			name:     "create collection",
+			path:     createCollectionPath,
+			response: `{"ResponseMetadata": {"RequestId": "req-1"}, "Result": {"resource_id": "mem-abc"}}`,
+			call: func(t *testing.T, c *Client) {
+				id, err := c.CreateCollection(ctx, &CreateCollectionRequest{
+					CollectionName:     "chat_memory",
+					BuiltinEventTypes:  []string{EventTypeSysEvent, EventTypeSysProfileCollect},
+					BuiltinEntityTypes: []string{EntityTypeSysProfile},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if id != "mem-abc" {
+					t.Errorf("resource ID = %q, want mem-abc", id)
+				}
+			},
+			wantBody: `{"CollectionName": "chat_memory", "BuiltinEventTypes": ["sys_event_v1", "sys_profile_collect_v1"], "BuiltinEntityTypes": ["sys_profile_v1"]}`,
+		},
+		{
+			name: "delete collection",
+			path: deleteCollectionPath,
+			call: func(t *testing.T, c *Client) {
+				if err := c.DeleteCollection(ctx, "chat_memory"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantBody: `{"collection_name": "chat_memory"}`,
+		},
+		{
+			name: "add messages",
+			path: addMessagesPath,
+			call: func(t *testing.T, c *Client) {
+				err := c.AddMessages(ctx, &AddMessagesRequest{
+					CollectionName: "chat_memory",
+					SessionID:      "session-1",
+					Messages:       []Message{{Role: "user", Content: "I play tennis"}, {Role: "assistant", Content: "Nice!"}},
+					Metadata:       MessagesMetadata{DefaultUserID: "1234", DefaultAssistantID: "assistant", Time: 1700000000000},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			},
+			wantBody: `{"collection_name": "chat_memory", "session_id": "session-1",
+				"messages": [{"role": "user", "content": "I play tennis"}, {"role": "assistant", "content": "Nice!"}],
+				"metadata": {"default_user_id": "1234", "default_assistant_id": "assistant", "time": 1700000000000}}`,
+		},
+		{
+			name: "search",
+			path: searchPath,
+			response: `{"code": 0, "data": {"collection_name": "chat_memory", "count": 1, "result_list": [
+				{"id": "m1", "memory_type": "sys_profile_v1", "memory_info": {"hobby": "tennis"}, "score": 0.9, "user_id": ["1234"]}]}}`,
+			call: func(t *testing.T, c *Client) {
+				result, err := c.Search(ctx, &SearchRequest{
+					CollectionName: "chat_memory",
+					Query:          "hobbies",
+					Limit:          5,
+					Filter:         SearchFilter{UserID: "1234", MemoryType: []string{EntityTypeSysProfile}},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result.Count != 1 || len(result.ResultList) != 1 || result.ResultList[0].InfoText() != "hobby: tennis" {
+					t.Errorf("result = %+v", result)
+				}
+			},
+			wantBody: `{"collection_name": "chat_memory", "query": "hobbies", "limit": 5, "filter": {"user_id": "1234", "memory_type": ["sys_profile_v1"]}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, fake := newTestClient(t, testSigner)
+			if tt.response != "" {
+				fake.responses[tt.path] = tt.response
+			}
+			tt.call(t, client)
+
+			requests := fake.Received()
+			if len(requests) != 1 {
+				t.Fatalf("server received %d requests, want 1", len(requests))
+			}
+			req := requests[0]
+			if req.Path != tt.path {
+				t.Errorf("path = %s, want %s", req.Path, tt.path)
+			}
+			assertJSON(t, req.Body, tt.wantBody)
+			for header, want := range map[string]string{
+				"Content-Type": "application/json",
+				"Accept":       "application/json",
+				"V-Account-Id": testSigner.AccountID,
+			} {
+				if got := req.Header.Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+			if !strings.HasPrefix(req.Header.Get("Authorization"), "HMAC-SHA256 Credential=ak/") {
+				t.Errorf("Authorization = %q", req.Header.Get("Authorization"))
+			}
+		})
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+	search := func(c *Client) error {
+		_, err := c.Search(ctx, &SearchRequest{CollectionName: "chat_memory", Query: "hobbies"})
+		return err
+	}
+	tests := []struct {
+		name     string
+		signer   *kbauth.Signer
+		path     string
+		status   int
+		response string
+		call     func(c *Client) error
+		wantErr  string
+	}{
+		{
+			name:     "error code",
+			path:     searchPath,
+			response: `{"code": 1000003, "message": "collection not found"}`,
+			call:     search,
+			wantErr:  "code 1000003, message: collection not found",
+		},
+		{
+			name:     "http status",
+			path:     deleteCollectionPath,
+			status:   http.StatusInternalServerError,
+			response: `{"code": 500, "message": "internal error"}`,
+			call:     func(c *Client) error { return c.DeleteCollection(ctx, "chat_memory") },
+			wantErr:  "returned status 500",
+		},
+		{
+			name:     "create collection error",
+			path:     createCollectionPath,
+			response: `{"ResponseMetadata": {"Error": {"Code": "InvalidParameter", "Message": "name taken"}}}`,
+			call: func(c *Client) error {
+				_, err := c.CreateCollection(ctx, &CreateCollectionRequest{CollectionName: "chat_memory"})
+				return err
+			},
+			wantErr: "InvalidParameter: name taken",
+		},
+		{
+			name:     "malformed response",
+			path:     searchPath,
+			response: `not json`,
+			call:     search,
+			wantErr:  "failed to unmarshal response",
+		},
+		{
+			name:    "wrong credentials",
+			signer:  &kbauth.Signer{AccessKey: "ak", SecretKey: "other", Region: "cn-beijing"},
+			call:    search,
+			wantErr: "returned status 401",
+		},
+		{
+			name: "no messages",
+			call: func(c *Client) error {
+				return c.AddMessages(ctx, &AddMessagesRequest{CollectionName: "chat_memory"})
+			},
+			wantErr: "at least one message is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signer := tt.signer
+			if signer == nil {
+				signer = testSigner
+			}
+			client, fake := newTestClient(t, signer)
+			if tt.path != "" {
+				fake.responses[tt.path] = tt.response
+				if tt.status != 0 {
+					fake.statuses[tt.path] = tt.status
+				}
+			}
+			err := tt.call(client)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package memorykb
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// CreateCollectionRequest describes a memory collection. Builtin types and
+// custom schemas can be combined.
+type CreateCollectionRequest struct {
+	CollectionName          string             `json:"CollectionName"`
+	Description             string             `json:"Description,omitempty"`
+	BuiltinEventTypes       []string           `json:"BuiltinEventTypes,omitempty"`
+	BuiltinEntityTypes      []string           `json:"BuiltinEntityTypes,omitempty"`
+	CustomEventTypeSchemas  []EventTypeSchema  `json:"CustomEventTypeSchemas,omitempty"`
+	CustomEntityTypeSchemas []EntityTypeSchema `json:"CustomEntityTypeSchemas,omitempty"`
+}
+
+// EventTypeSchema defines what is extracted from each conversation.
+type EventTypeSchema struct {
+	EventType   string           `json:"EventType"`
+	Version     string           `json:"Version,omitempty"`
+	Description string           `json:"Description"`
+	Properties  []PropertySchema `json:"Properties"`
+}
+
+// EntityTypeSchema defines long-lived state aggregated from events.
+type EntityTypeSchema struct {
+	EntityType           string           `json:"EntityType"`
+	AssociatedEventTypes []string         `json:"AssociatedEventTypes,omitempty"`
+	Description          string           `json:"Description"`
+	Properties           []PropertySchema `json:"Properties"`
+}
+
+// PropertySchema is one field of an event or entity type. PropertyValueType
+// is one of int64, list<int64>, string, list<string>, float32 or bool.
+type PropertySchema struct {
+	PropertyName        string               `json:"PropertyName"`
+	PropertyValueType   string               `json:"PropertyValueType"`
+	Description         string               `json:"Description"`
+	DefaultValue        interface{}          `json:"DefaultValue,omitempty"`
+	IsPrimaryKey        bool                 `json:"IsPrimaryKey,omitempty"`
+	UseProvided         bool                 `json:"UseProvided,omitempty"`
+	AggregateExpression *AggregateExpression `json:"AggregateExpression,omitempty"`
+}
+
+// AggregateExpression derives an entity property from event properties.
+type AggregateExpression struct {
+	Op                string `json:"Op"`
+	EventType         string `json:"EventType"`
+	EventPropertyName string `json:"EventPropertyName"`
+}
+
+// AddMessagesRequest is one conversation session to ingest.
+type AddMessagesRequest struct {
+	CollectionName string           `json:"collection_name"`
+	SessionID      string           `json:"session_id"`
+	Messages       []Message        `json:"messages"`
+	Metadata       MessagesMetadata `json:"metadata"`
+	Entities       []EntityScope    `json:"entities,omitempty"`
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// MessagesMetadata attributes the messages of a session. Time is the session
+// start in milliseconds, see Timestamp.
+type MessagesMetadata struct {
+	DefaultUserID      string `json:"default_user_id"`
+	DefaultAssistantID string `json:"default_assistant_id"`
+	Time               int64  `json:"time"`
+	GroupID            string `json:"group_id,omitempty"`
+}
+
+// EntityScope pins the entities a session may update, identified by their
+// primary key and UseProvided properties.
+type EntityScope struct {
+	EntityType  string                   `json:"entity_type"`
+	EntityScope []map[string]interface{} `json:"entity_scope"`
+}
+
+type SearchRequest struct {
+	CollectionName string       `json:"collection_name"`
+	Query          string       `json:"query"`
+	Limit          int          `json:"limit,omitempty"`
+	Filter         SearchFilter `json:"filter"`
+}
+
+// SearchFilter restricts a search to one user's memories of the given types.
+type SearchFilter struct {
+	UserID      string   `json:"user_id,omitempty"`
+	AssistantID string   `json:"assistant_id,omitempty"`
+	GroupID     string   `json:"group_id,omitempty"`
+	MemoryType  []string `json:"memory_type,omitempty"`
+}
+
+type SearchResult struct {
+	CollectionName string   `json:"collection_name"`
+	Count          int      `json:"count"`
+	ResultList     []Memory `json:"result_list"`
+	TokenUsage     int      `json:"token_usage"`
+}
+
+type Memory struct {
+	ID          string          `json:"id"`
+	MemoryType  string          `json:"memory_type"`
+	MemoryInfo  json.RawMessage `json:"memory_info"`
+	Score       float64         `json:"score"`
+	SessionID   string          `json:"session_id"`
+	UserID      []string        `json:"user_id"`
+	AssistantID []string        `json:"assistant_id"`
+	GroupID     string          `json:"group_id"`
+	Time        int64           `json:"time"`
+	Status      string          `json:"status"`
+}
+
+// InfoText renders MemoryInfo for use in a prompt. String memories are
+// returned as-is; structured ones become sorted "key: value" lines.
+func (m *Memory) InfoText() string {
+	var text string
+	if err := json.Unmarshal(m.MemoryInfo, &text); err == nil {
+		return text
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(m.MemoryInfo, &fields); err != nil {
+		return string(m.MemoryInfo)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, 0, len(keys))
+	for _, key := range keys {
+		lines = append(lines, fmt.Sprintf("%s: %v", key, fields[key]))
+	}
+	return strings.Join(lines, "\n")
+}
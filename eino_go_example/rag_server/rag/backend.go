@@ -0,0 +1,57 @@
+// Package rag holds the retrieval-augmented generation service shared by all
+// knowledge backends: the query, streaming and document HTTP handlers, prompt
+// building, citations and conversation history.
+package rag
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cloudwego/eino/schema"
+
+	"rag-backend/chunking"
+)
+
+// Backend retrieves the chunks relevant to a query from a knowledge store.
+// Document management is optional; see DocumentAdder, DocumentLister and
+// DocumentDeleter. Handlers answer 501 when the backend lacks the capability.
+type Backend interface {
+	// Name identifies the backend in logs and responses.
+	Name() string
+	// Search returns the chunks for query. opts may be nil; backends ignore
+	// options they do not support.
+	Search(ctx context.Context, query string, opts *SearchOptions) ([]*schema.Document, error)
+}
+
+// ErrInvalidDocument is returned by AddDocument for documents the backend
+// cannot ingest as given.
+var ErrInvalidDocument = errors.New("invalid document")
+
+// DocumentAdder is implemented by backends that can ingest documents.
+type DocumentAdder interface {
+	AddDocument(ctx context.Context, doc *AddDocumentRequest) (*AddDocumentResult, error)
+}
+
+// RemoteParser is implemented by backends whose service parses uploaded
+// files itself, such as ragKB. Uploads to other backends are parsed locally,
+// and URLs downloaded, before AddDocument.
+type RemoteParser interface {
+	ParsesDocuments() bool
+}
+
+// ChunkingBackend is implemented by backends that chunk documents locally
+// before indexing them, such as vikingdb. Backends whose service chunks
+// uploads, such as ragKB, cannot preview chunks.
+type ChunkingBackend interface {
+	ChunkingConfig() chunking.Config
+}
+
+// DocumentLister is implemented by backends that can page through their documents.
+type DocumentLister interface {
+	ListDocuments(ctx context.Context, opts ListDocumentsOptions) (*ListDocumentsResult, error)
+}
+
+// DocumentDeleter is implemented by backends that can remove documents.
+type DocumentDeleter interface {
+	DeleteDocuments(ctx context.Context, req *DeleteDocumentsRequest) (*DeleteDocumentsResult, error)
+}
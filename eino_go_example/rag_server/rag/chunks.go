@@ -0,0 +1,179 @@
+package rag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/chunking"
+	"rag-backend/docparse"
+)
+
+// bindDocument reads an upload from a JSON body or a multipart form, as
+// described on UploadDocument. It answers the request itself when the
+// upload is malformed, or 413 when the body is larger than a document may be.
+func bindDocument(c *gin.Context) (*AddDocumentRequest, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, docparse.MaxFetchSize)
+
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		var req UploadRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			abortBind(c, err.Error(), err)
+			return nil, false
+		}
+		if req.Content == "" && req.URL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "either content or url is required"})
+			return nil, false
+		}
+
+		return &AddDocumentRequest{
+			DocID:    req.DocID,
+			DocName:  req.DocName,
+			DocType:  req.DocType,
+			URL:      req.URL,
+			Content:  []byte(req.Content),
+			Metadata: req.Metadata,
+			Chunking: req.Chunking,
+		}, true
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		abortBind(c, "file is required", err)
+		return nil, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to open file: %v", err)})
+		return nil, false
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Failed to read file: %v", err)})
+		return nil, false
+	}
+
+	var metadata map[string]interface{}
+	if metaStr := c.PostForm("metadata"); metaStr != "" {
+		if err := json.Unmarshal([]byte(metaStr), &metadata); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "metadata must be a JSON object"})
+			return nil, false
+		}
+	}
+
+	var chunkingConfig chunking.Config
+	if chunkingStr := c.PostForm("chunking"); chunkingStr != "" {
+		if err := json.Unmarshal([]byte(chunkingStr), &chunkingConfig); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "chunking must be a JSON object"})
+			return nil, false
+		}
+	}
+
+	docName := c.PostForm("doc_name")
+	if docName == "" {
+		docName = fileHeader.Filename
+	}
+
+	return &AddDocumentRequest{
+		DocID:       c.PostForm("doc_id"),
+		DocName:     docName,
+		DocType:     c.PostForm("doc_type"),
+		ContentType: fileHeader.Header.Get("Content-Type"),
+		Content:     content,
+		Metadata:    metadata,
+		Chunking:    chunkingConfig,
+	}, true
+}
+
+// abortBind answers an upload that could not be read with 400 and message,
+// or with 413 if err is from reading past the size limit.
+func abortBind(c *gin.Context, message string, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("upload is larger than %d bytes", tooLarge.Limit)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": message})
+}
+
+// parseDocumentOrAbort parses doc like parseDocument, answering the request
+// when that fails.
+func (s *Service) parseDocumentOrAbort(c *gin.Context, doc *AddDocumentRequest) bool {
+	err := s.parseDocument(c.Request.Context(), doc)
+	if err == nil {
+		return true
+	}
+	log.Printf("Document parsing failed: %v", err)
+	status := http.StatusBadGateway
+	if errors.Is(err, ErrInvalidDocument) || errors.Is(err, docparse.ErrUnsupportedType) {
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, gin.H{
+		"error":   "Document parsing failed",
+		"message": err.Error(),
+	})
+	return false
+}
+
+// PreviewChunks returns the chunks an upload of the same body would be
+// indexed as, with their estimated token counts, without writing anything.
+// The chunking settings are the backend's, overridden by the request's.
+func (s *Service) PreviewChunks(c *gin.Context) {
+	backend, ok := s.backend.(ChunkingBackend)
+	if !ok {
+		s.notImplemented(c, "Chunk preview")
+		return
+	}
+
+	doc, ok := bindDocument(c)
+	if !ok {
+		return
+	}
+	config := backend.ChunkingConfig().Merge(doc.Chunking).WithDefaults()
+	if err := config.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !s.parseDocumentOrAbort(c, doc) {
+		return
+	}
+
+	splitter, err := chunking.New(c.Request.Context(), config)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chunker", "message": err.Error()})
+		return
+	}
+	chunks, err := splitter.Transform(c.Request.Context(), doc.Sections)
+	if err != nil {
+		log.Printf("Chunk preview failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to split document", "message": err.Error()})
+		return
+	}
+
+	response := PreviewChunksResponse{
+		Chunking: config,
+		Count:    len(chunks),
+		Chunks:   make([]ChunkPreview, 0, len(chunks)),
+	}
+	for i, chunk := range chunks {
+		tokens := chunking.CountTokens(chunk.Content)
+		response.TotalTokens += tokens
+		response.Chunks = append(response.Chunks, ChunkPreview{
+			Index:    i,
+			Content:  chunk.Content,
+			Chars:    len([]rune(chunk.Content)),
+			Tokens:   tokens,
+			Metadata: chunk.MetaData,
+		})
+	}
+	c.JSON(http.StatusOK, response)
+}
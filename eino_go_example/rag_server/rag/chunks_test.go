@@ -0,0 +1,90 @@
+package rag
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/chunking"
+)
+
+// chunkingBackend is a stubBackend that chunks locally with config.
+type chunkingBackend struct {
+	stubBackend
+	config chunking.Config
+}
+
+func (b *chunkingBackend) ChunkingConfig() chunking.Config { return b.config }
+
+func TestPreviewChunksOverrides(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	content := strings.Repeat("Short sentences fill the text. ", 20)
+	overlap := 10
+
+	tests := []struct {
+		name     string
+		backend  chunking.Config
+		override string
+		want     chunking.Config
+		wantCode int
+	}{
+		{name: "defaults", override: `{}`, want: chunking.Config{Strategy: chunking.StrategyRecursive, ChunkSize: 800, Overlap: intPtr(100)}, wantCode: http.StatusOK},
+		{name: "small chunk size", override: `{"chunk_size": 50}`, want: chunking.Config{Strategy: chunking.StrategyRecursive, ChunkSize: 50, Overlap: intPtr(25)}, wantCode: http.StatusOK},
+		{name: "chunk size of the default overlap", override: `{"chunk_size": 100}`, want: chunking.Config{Strategy: chunking.StrategyRecursive, ChunkSize: 100, Overlap: intPtr(50)}, wantCode: http.StatusOK},
+		{name: "small sentence chunks", override: `{"strategy": "sentence", "chunk_size": 80}`, want: chunking.Config{Strategy: chunking.StrategySentence, ChunkSize: 80, Overlap: intPtr(40)}, wantCode: http.StatusOK},
+		{name: "configured strategy kept", backend: chunking.Config{Strategy: chunking.StrategyMarkdown}, override: `{"chunk_size": 50}`, want: chunking.Config{Strategy: chunking.StrategyMarkdown, ChunkSize: 50, Overlap: intPtr(25)}, wantCode: http.StatusOK},
+		{name: "configured overlap kept", backend: chunking.Config{Overlap: &overlap}, override: `{"chunk_size": 50}`, want: chunking.Config{Strategy: chunking.StrategyRecursive, ChunkSize: 50, Overlap: intPtr(10)}, wantCode: http.StatusOK},
+		{name: "overlap too large", override: `{"chunk_size": 50, "overlap": 50}`, wantCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&chunkingBackend{stubBackend: stubBackend{name: "local"}, config: tt.backend}, nil, &Config{})
+			router := NewRouter(service)
+
+			body := `{"content": ` + strings.TrimSpace(mustJSON(t, content)) + `, "chunking": ` + tt.override + `}`
+			req := httptest.NewRequest(http.MethodPost, "/documents/preview-chunks", bytes.NewReader([]byte(body)))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.wantCode, w.Body)
+			}
+			if w.Code != http.StatusOK {
+				return
+			}
+
+			var response PreviewChunksResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatal(err)
+			}
+			got := response.Chunking
+			if got.Strategy != tt.want.Strategy || got.ChunkSize != tt.want.ChunkSize || got.Overlap == nil || *got.Overlap != *tt.want.Overlap {
+				t.Errorf("chunking = %+v (overlap %v), want %+v (overlap %d)", got, got.Overlap, tt.want, *tt.want.Overlap)
+			}
+			if response.Count == 0 {
+				t.Error("preview has no chunks")
+			}
+			for _, chunk := range response.Chunks {
+				if tt.want.Strategy != chunking.StrategyToken && chunk.Chars > tt.want.ChunkSize {
+					t.Errorf("chunk %d has %d characters, more than %d", chunk.Index, chunk.Chars, tt.want.ChunkSize)
+				}
+			}
+		})
+	}
+}
+
+func intPtr(n int) *int { return &n }
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -0,0 +1,113 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Citation links a [n] marker in a generated answer to the retrieved chunk
+// numbered n in the prompt.
+type Citation struct {
+	Index          int    `json:"index"`
+	ChunkID        string `json:"chunk_id"`
+	DocID          string `json:"doc_id,omitempty"`
+	DocName        string `json:"doc_name,omitempty"`
+	Title          string `json:"title,omitempty"`
+	ChunkTitle     string `json:"chunk_title,omitempty"`
+	AttachmentLink string `json:"attachment_link,omitempty"`
+}
+
+// citationMarker matches [1] as well as grouped markers such as [1, 3].
+var citationMarker = regexp.MustCompile(`\s?\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+const citationInstructions = "You are a helpful assistant that answers questions based on the provided context. " +
+	"The context is split into numbered sources. After every sentence that uses a source, cite it with its number " +
+	"in square brackets, for example [1] or [1, 3]. Only cite sources that appear in the context."
+
+// formatNumberedContext renders the chunks as numbered sources, 1-based, so the
+// model can cite them.
+func formatNumberedContext(docs []*schema.Document) string {
+	parts := make([]string, len(docs))
+	for i, doc := range docs {
+		header := fmt.Sprintf("[%d]", i+1)
+		var labels []string
+		for _, key := range []string{"doc_name", "title", "chunk_title"} {
+			if value, ok := doc.MetaData[key].(string); ok && value != "" {
+				labels = append(labels, value)
+			}
+		}
+		if len(labels) > 0 {
+			header += " " + strings.Join(labels, " / ")
+		}
+		content := doc.Content
+		if fields, ok := doc.MetaData["table_chunk_fields"].([]TableField); ok && len(fields) > 0 {
+			content = formatTable(fields)
+		}
+		parts[i] = header + "\n" + content
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// formatTable renders a table chunk as a one-row markdown table.
+func formatTable(fields []TableField) string {
+	escape := strings.NewReplacer("|", "\\|", "\n", " ")
+	names := make([]string, len(fields))
+	values := make([]string, len(fields))
+	separators := make([]string, len(fields))
+	for i, field := range fields {
+		names[i] = escape.Replace(field.Name)
+		values[i] = escape.Replace(field.Value)
+		separators[i] = "---"
+	}
+	return "| " + strings.Join(names, " | ") + " |\n" +
+		"| " + strings.Join(separators, " | ") + " |\n" +
+		"| " + strings.Join(values, " | ") + " |"
+}
+
+// resolveCitations validates the [n] markers in answer against docs. Markers
+// pointing at chunks that do not exist are removed; the remaining ones are
+// returned as citations in order of first use.
+func resolveCitations(answer string, docs []*schema.Document) (string, []Citation) {
+	var citations []Citation
+	cited := make(map[int]bool)
+
+	cleaned := citationMarker.ReplaceAllStringFunc(answer, func(marker string) string {
+		groups := citationMarker.FindStringSubmatch(marker)
+		var valid []string
+		for _, part := range strings.Split(groups[1], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || n < 1 || n > len(docs) {
+				continue
+			}
+			valid = append(valid, strconv.Itoa(n))
+			if !cited[n] {
+				cited[n] = true
+				citations = append(citations, newCitation(n, docs[n-1]))
+			}
+		}
+		if len(valid) == 0 {
+			return ""
+		}
+		// Keep the whitespace the pattern consumed in front of the marker
+		prefix := marker[:strings.Index(marker, "[")]
+		return prefix + "[" + strings.Join(valid, ", ") + "]"
+	})
+
+	return cleaned, citations
+}
+
+func newCitation(index int, doc *schema.Document) Citation {
+	citation := Citation{Index: index, ChunkID: doc.ID}
+	citation.DocID, _ = doc.MetaData["doc_id"].(string)
+	citation.DocName, _ = doc.MetaData["doc_name"].(string)
+	citation.Title, _ = doc.MetaData["title"].(string)
+	citation.ChunkTitle, _ = doc.MetaData["chunk_title"].(string)
+	if links, ok := doc.MetaData["attachment_links"].([]string); ok && len(links) > 0 {
+		citation.AttachmentLink = links[0]
+	}
+	return citation
+}
@@ -0,0 +1,91 @@
+package rag
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ConversationStore keeps recent message history in memory, keyed by
+// conversation ID, so clients can send only the newest turn.
+type ConversationStore struct {
+	mu            sync.Mutex
+	ttl           time.Duration
+	maxMessages   int
+	conversations map[string]*conversation
+}
+
+type conversation struct {
+	messages  []Message
+	updatedAt time.Time
+}
+
+func NewConversationStore(ttl time.Duration, maxMessages int) *ConversationStore {
+	return &ConversationStore{
+		ttl:           ttl,
+		maxMessages:   maxMessages,
+		conversations: make(map[string]*conversation),
+	}
+}
+
+// History returns a copy of the stored messages, or nil for an unknown or
+// expired conversation.
+func (s *ConversationStore) History(id string) []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	conv, ok := s.conversations[id]
+	if !ok || time.Since(conv.updatedAt) > s.ttl {
+		return nil
+	}
+	return append([]Message(nil), conv.messages...)
+}
+
+// Append records messages for the conversation, keeping only the newest
+// maxMessages, and drops conversations that have expired.
+func (s *ConversationStore) Append(id string, messages ...Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for key, conv := range s.conversations {
+		if now.Sub(conv.updatedAt) > s.ttl {
+			delete(s.conversations, key)
+		}
+	}
+
+	conv, ok := s.conversations[id]
+	if !ok {
+		conv = &conversation{}
+		s.conversations[id] = conv
+	}
+	conv.messages = append(conv.messages, messages...)
+	if len(conv.messages) > s.maxMessages {
+		conv.messages = conv.messages[len(conv.messages)-s.maxMessages:]
+	}
+	conv.updatedAt = now
+}
+
+func newConversationID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("conv_%d", time.Now().UnixNano())
+	}
+	return "conv_" + hex.EncodeToString(b)
+}
+
+// ValidateMessages checks client-supplied history before it is forwarded to
+// the backend and the chat model.
+func ValidateMessages(messages []Message) error {
+	for i, msg := range messages {
+		if msg.Role != "user" && msg.Role != "assistant" {
+			return fmt.Errorf("messages[%d]: role must be user or assistant", i)
+		}
+		if msg.Content == "" {
+			return fmt.Errorf("messages[%d]: content is required", i)
+		}
+	}
+	return nil
+}
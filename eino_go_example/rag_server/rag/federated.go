@@ -0,0 +1,260 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+// Fusion methods for federated search
+const (
+	// FusionRRF ranks chunks by reciprocal rank fusion, which needs no
+	// comparable scores across sources.
+	FusionRRF = "rrf"
+	// FusionScore min-max normalises each source's scores and sums them.
+	FusionScore = "score"
+)
+
+// rrfK dampens the weight of top ranks in reciprocal rank fusion; 60 is the
+// value from the original paper.
+const rrfK = 60
+
+// ErrUnknownBackend is returned for a source naming a backend the server does
+// not have.
+var ErrUnknownBackend = errors.New("unknown backend")
+
+// Source is one search target of a federated query: a backend, by default
+// the primary one, optionally narrowed to a ragKB project and collection.
+type Source struct {
+	Backend    string `json:"backend,omitempty"`
+	Project    string `json:"project,omitempty"`
+	Collection string `json:"collection,omitempty"`
+}
+
+// SourceResult reports how one source of a federated query fared.
+type SourceResult struct {
+	Source    string `json:"source"`
+	Count     int    `json:"count"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Retrieval is the outcome of a search. Sources is only set for federated
+// queries, which succeed as long as one source does.
+type Retrieval struct {
+	Documents []*schema.Document
+	Sources   []SourceResult
+}
+
+// federated reports whether the options ask for a federated search.
+func (o *SearchOptions) federated() bool {
+	return len(o.Sources) > 0 || o.Fusion != ""
+}
+
+// name labels a source in results, e.g. "ragkb:hr" or "ragkb:proj/hr".
+func (src Source) name() string {
+	name := src.Backend
+	switch {
+	case src.Project != "" && src.Collection != "":
+		name += ":" + src.Project + "/" + src.Collection
+	case src.Project != "":
+		name += ":" + src.Project + "/"
+	case src.Collection != "":
+		name += ":" + src.Collection
+	}
+	return name
+}
+
+// federatedSources expands the options into the sources to search: the
+// explicit ones, or one per requested collection of the primary backend.
+func (s *Service) federatedSources(opts *SearchOptions) []Source {
+	if len(opts.Sources) > 0 {
+		sources := make([]Source, len(opts.Sources))
+		for i, src := range opts.Sources {
+			if src.Backend == "" {
+				src.Backend = s.backend.Name()
+			}
+			sources[i] = src
+		}
+		return sources
+	}
+
+	collections := opts.CollectionNames()
+	if len(collections) == 0 {
+		return []Source{{Backend: s.backend.Name(), Project: opts.Project}}
+	}
+	sources := make([]Source, len(collections))
+	for i, collection := range collections {
+		sources[i] = Source{Backend: s.backend.Name(), Project: opts.Project, Collection: collection}
+	}
+	return sources
+}
+
+// federatedSearch searches every source concurrently and fuses the results.
+// It fails only when every source does.
+func (s *Service) federatedSearch(ctx context.Context, query string, opts *SearchOptions) (*Retrieval, error) {
+	sources := s.federatedSources(opts)
+	results := make([]SourceResult, len(sources))
+	ranked := make([][]*schema.Document, len(sources))
+	errs := make([]error, len(sources))
+
+	var wg sync.WaitGroup
+	for i, src := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			ranked[i], errs[i] = s.searchSource(ctx, query, src, opts)
+			results[i] = SourceResult{
+				Source:    src.name(),
+				Count:     len(ranked[i]),
+				LatencyMS: time.Since(start).Milliseconds(),
+			}
+			if errs[i] != nil {
+				results[i].Error = errs[i].Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	if failed == len(sources) {
+		return nil, fmt.Errorf("all %d sources failed: %w", failed, errors.Join(errs...))
+	}
+
+	limit := 0
+	if opts.TopK != nil {
+		limit = *opts.TopK
+	} else {
+		// Without top_k return as many chunks as the largest source did
+		for _, docs := range ranked {
+			limit = max(limit, len(docs))
+		}
+	}
+
+	names := make([]string, len(sources))
+	for i, src := range sources {
+		names[i] = src.name()
+	}
+	return &Retrieval{
+		Documents: fuse(ranked, names, opts.Fusion, limit),
+		Sources:   results,
+	}, nil
+}
+
+// searchSource searches one source, applying min_score to its own scores
+// since fused scores are not comparable to them.
+func (s *Service) searchSource(ctx context.Context, query string, src Source, opts *SearchOptions) ([]*schema.Document, error) {
+	backend, ok := s.backends[src.Backend]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownBackend, src.Backend)
+	}
+
+	sourceOpts := *opts
+	sourceOpts.Sources = nil
+	sourceOpts.Fusion = ""
+	sourceOpts.Project = src.Project
+	sourceOpts.Collection = src.Collection
+	sourceOpts.Collections = nil
+	docs, err := backend.Search(ctx, query, &sourceOpts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.MinScore != nil {
+		docs = filterByScore(docs, *opts.MinScore)
+	}
+	return docs, nil
+}
+
+// fuse merges ranked result lists into one, best first. Chunks with the same
+// content are merged into the first one seen and list every source that
+// returned them under "sources"; their original score is kept as
+// "source_score". A source counts once per chunk, at its best rank. The
+// documents returned are copies, so the backends' results are not modified.
+func fuse(ranked [][]*schema.Document, names []string, method string, limit int) []*schema.Document {
+	type fused struct {
+		doc     *schema.Document
+		score   float64
+		sources []string
+	}
+
+	byContent := make(map[[sha256.Size]byte]*fused)
+	var order []*fused
+	for i, docs := range ranked {
+		low, high := scoreRange(docs)
+		seen := make(map[[sha256.Size]byte]bool, len(docs))
+		rank := 0
+		for _, doc := range docs {
+			key := sha256.Sum256([]byte(strings.TrimSpace(doc.Content)))
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			var contribution float64
+			if method == FusionScore {
+				contribution = 1
+				if high > low {
+					contribution = (doc.Score() - low) / (high - low)
+				}
+			} else {
+				contribution = 1 / float64(rrfK+rank+1)
+			}
+			rank++
+
+			entry, ok := byContent[key]
+			if !ok {
+				entry = &fused{doc: doc}
+				byContent[key] = entry
+				order = append(order, entry)
+			}
+			entry.score += contribution
+			entry.sources = append(entry.sources, names[i])
+		}
+	}
+
+	sort.SliceStable(order, func(a, b int) bool {
+		return order[a].score > order[b].score
+	})
+	if limit > 0 && len(order) > limit {
+		order = order[:limit]
+	}
+
+	docs := make([]*schema.Document, len(order))
+	for i, entry := range order {
+		doc := *entry.doc
+		doc.MetaData = make(map[string]interface{}, len(entry.doc.MetaData)+2)
+		for key, value := range entry.doc.MetaData {
+			doc.MetaData[key] = value
+		}
+		doc.MetaData["source_score"] = entry.doc.Score()
+		doc.MetaData["sources"] = entry.sources
+		docs[i] = doc.WithScore(entry.score)
+	}
+	return docs
+}
+
+func scoreRange(docs []*schema.Document) (low, high float64) {
+	for i, doc := range docs {
+		score := doc.Score()
+		if i == 0 || score < low {
+			low = score
+		}
+		if i == 0 || score > high {
+			high = score
+		}
+	}
+	return low, high
+}
@@ -0,0 +1,223 @@
+package rag
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/cloudwego/eino/schema"
+)
+
+func scored(content string, score float64) *schema.Document {
+	return (&schema.Document{ID: content, Content: content}).WithScore(score)
+}
+
+func contents(docs []*schema.Document) []string {
+	out := make([]string, len(docs))
+	for i, doc := range docs {
+		out[i] = doc.Content
+	}
+	return out
+}
+
+func TestFuse(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranked [][]*schema.Document
+		method string
+		limit  int
+		want   []string
+	}{
+		{
+			name: "rrf favours chunks found by several sources",
+			ranked: [][]*schema.Document{
+				{scored("a", 9), scored("b", 8), scored("c", 7)},
+				{scored("c", 0.9), scored("d", 0.8)},
+			},
+			method: FusionRRF,
+			want:   []string{"c", "a", "b", "d"},
+		},
+		{
+			name: "rrf ignores score scales",
+			ranked: [][]*schema.Document{
+				{scored("a", 100)},
+				{scored("b", 0.1), scored("c", 0.01)},
+			},
+			method: FusionRRF,
+			want:   []string{"a", "b", "c"},
+		},
+		{
+			name: "score normalises each source",
+			ranked: [][]*schema.Document{
+				{scored("a", 100), scored("b", 50), scored("c", 0)},
+				{scored("b", 0.9), scored("d", 0.1)},
+			},
+			method: FusionScore,
+			want:   []string{"b", "a", "c", "d"},
+		},
+		{
+			name: "limit",
+			ranked: [][]*schema.Document{
+				{scored("a", 3), scored("b", 2), scored("c", 1)},
+			},
+			limit: 2,
+			want:  []string{"a", "b"},
+		},
+		{
+			name: "a source counts once per chunk",
+			ranked: [][]*schema.Document{
+				{scored("a", 3), scored("a", 2), scored("a", 1)},
+				{scored("b", 3), scored("c", 2)},
+			},
+			method: FusionRRF,
+			want:   []string{"a", "b", "c"},
+		},
+		{
+			name: "whitespace does not split duplicates",
+			ranked: [][]*schema.Document{
+				{scored("a", 1)},
+				{scored(" a\n", 1)},
+			},
+			want: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := make([]string, len(tt.ranked))
+			for i := range names {
+				names[i] = string(rune('x' + i))
+			}
+			got := fuse(tt.ranked, names, tt.method, tt.limit)
+			if !reflect.DeepEqual(contents(got), tt.want) {
+				t.Errorf("fuse = %q, want %q", contents(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestFuseMetadata(t *testing.T) {
+	docs := fuse([][]*schema.Document{
+		{scored("a", 0.7)},
+		{scored("a", 12)},
+	}, []string{"ragkb:hr", "vikingdb"}, FusionRRF, 0)
+
+	if len(docs) != 1 {
+		t.Fatalf("fused %d documents, want 1", len(docs))
+	}
+	if got := docs[0].MetaData["source_score"]; got != 0.7 {
+		t.Errorf("source_score = %v, want the first source's 0.7", got)
+	}
+	if got := docs[0].MetaData["sources"]; !reflect.DeepEqual(got, []string{"ragkb:hr", "vikingdb"}) {
+		t.Errorf("sources = %v", got)
+	}
+	if want := 2.0 / (rrfK + 1); docs[0].Score() != want {
+		t.Errorf("score = %v, want %v", docs[0].Score(), want)
+	}
+}
+
+func TestFuseDuplicatesWithinSource(t *testing.T) {
+	docs := fuse([][]*schema.Document{
+		{scored("a", 0.9), scored("b", 0.8), scored(" a", 0.7)},
+		{scored("b", 0.9)},
+	}, []string{"primary", "extra"}, FusionRRF, 0)
+
+	if got := contents(docs); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Fatalf("fuse = %q, want b, found by both sources, then a", got)
+	}
+	if got := docs[1].MetaData["sources"]; !reflect.DeepEqual(got, []string{"primary"}) {
+		t.Errorf("sources = %v, want primary once", got)
+	}
+	if want := 1.0 / (rrfK + 1); docs[1].Score() != want {
+		t.Errorf("score = %v, want %v for its best rank only", docs[1].Score(), want)
+	}
+	if want := 1/float64(rrfK+2) + 1/float64(rrfK+1); docs[0].Score() != want {
+		t.Errorf("score = %v, want %v", docs[0].Score(), want)
+	}
+}
+
+func TestFuseCopiesDocuments(t *testing.T) {
+	original := scored("a", 0.7)
+	original.MetaData["doc_id"] = "guide"
+	docs := fuse([][]*schema.Document{{original}}, []string{"primary"}, FusionRRF, 0)
+
+	if docs[0] == original {
+		t.Fatal("fuse returned the backend's document")
+	}
+	if got := docs[0].MetaData["doc_id"]; got != "guide" {
+		t.Errorf("doc_id = %v, want the metadata copied", got)
+	}
+	if len(original.MetaData) != 2 || original.Score() != 0.7 {
+		t.Errorf("backend document was modified: %v", original.MetaData)
+	}
+}
+
+// stubBackend returns fixed results for every search.
+type stubBackend struct {
+	name string
+	docs []*schema.Document
+	err  error
+}
+
+func (b *stubBackend) Name() string { return b.name }
+
+func (b *stubBackend) Search(ctx context.Context, query string, opts *SearchOptions) ([]*schema.Document, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	docs := make([]*schema.Document, len(b.docs))
+	for i, doc := range b.docs {
+		docs[i] = scored(doc.Content, doc.Score())
+	}
+	return docs, nil
+}
+
+func TestRetrieveMinScore(t *testing.T) {
+	primary := &stubBackend{name: "primary", docs: []*schema.Document{scored("a", 0.9), scored("b", 0.6), scored("c", 0.2)}}
+	extra := &stubBackend{name: "extra", docs: []*schema.Document{scored("d", 0.8), scored("e", 0.1)}}
+	service := NewService(primary, nil, &Config{ExtraBackends: []Backend{extra}})
+	minScore, topK := 0.5, 10
+
+	tests := []struct {
+		name        string
+		opts        SearchOptions
+		want        []string
+		wantSources []SourceResult
+	}{
+		{
+			name: "single backend",
+			opts: SearchOptions{MinScore: &minScore},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "federated filters each source before fusing",
+			opts: SearchOptions{TopK: &topK, MinScore: &minScore, Sources: []Source{{Backend: "primary"}, {Backend: "extra"}}},
+			want: []string{"a", "d", "b"},
+			wantSources: []SourceResult{
+				{Source: "primary", Count: 2},
+				{Source: "extra", Count: 1},
+			},
+		},
+		{
+			name: "federated without min_score",
+			opts: SearchOptions{TopK: &topK, Sources: []Source{{Backend: "primary"}, {Backend: "extra"}}},
+			want: []string{"a", "d", "b", "e", "c"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retrieval, err := service.Retrieve(context.Background(), "q", &tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(contents(retrieval.Documents), tt.want) {
+				t.Errorf("documents = %q, want %q", contents(retrieval.Documents), tt.want)
+			}
+			for i, want := range tt.wantSources {
+				got := retrieval.Sources[i]
+				if got.Source != want.Source || got.Count != want.Count {
+					t.Errorf("source %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
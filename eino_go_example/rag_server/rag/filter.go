@@ -0,0 +1,208 @@
+package rag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrUnsupportedOption is returned by backends for search options they
+// cannot honour, such as a filter on a backend without metadata.
+var ErrUnsupportedOption = errors.New("search option not supported")
+
+// ErrMissingOption is returned by backends when a search lacks an option
+// they require, such as the user whose memories to search.
+var ErrMissingOption = errors.New("required search option missing")
+
+// ErrUnknownCollection is returned when a request names a collection the
+// backend does not have.
+var ErrUnknownCollection = errors.New("unknown collection")
+
+// Filter restricts a search to chunks whose metadata matches. Must and
+// MustNot map a field to a value or a list of values (any of them matches);
+// Range bounds numeric fields. All conditions must hold.
+type Filter struct {
+	Must    map[string]interface{} `json:"must,omitempty"`
+	MustNot map[string]interface{} `json:"must_not,omitempty"`
+	Range   map[string]Range       `json:"range,omitempty"`
+}
+
+// Range bounds a numeric field; nil bounds are open.
+type Range struct {
+	GT  *float64 `json:"gt,omitempty"`
+	GTE *float64 `json:"gte,omitempty"`
+	LT  *float64 `json:"lt,omitempty"`
+	LTE *float64 `json:"lte,omitempty"`
+}
+
+// IsEmpty reports whether the filter has no conditions.
+func (f *Filter) IsEmpty() bool {
+	return f == nil || len(f.Must)+len(f.MustNot)+len(f.Range) == 0
+}
+
+// Validate checks that values are scalars or lists of scalars and that every
+// range has a bound.
+func (f *Filter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	for clause, conds := range map[string]map[string]interface{}{"must": f.Must, "must_not": f.MustNot} {
+		for field, value := range conds {
+			if _, err := filterValues(value); err != nil {
+				return fmt.Errorf("filter.%s.%s: %w", clause, field, err)
+			}
+		}
+	}
+	for field, r := range f.Range {
+		if r.GT == nil && r.GTE == nil && r.LT == nil && r.LTE == nil {
+			return fmt.Errorf("filter.range.%s needs at least one of gt, gte, lt, lte", field)
+		}
+	}
+	return nil
+}
+
+// DSL translates the filter into the VikingDB filter DSL, which ragKB also
+// accepts as doc_filter. Conditions are sorted by field so the output is
+// stable. It returns nil for an empty filter.
+func (f *Filter) DSL() map[string]interface{} {
+	if f.IsEmpty() {
+		return nil
+	}
+
+	var conds []map[string]interface{}
+	for _, field := range sortedKeys(f.Must) {
+		values, _ := filterValues(f.Must[field])
+		conds = append(conds, map[string]interface{}{"op": "must", "field": field, "conds": values})
+	}
+	for _, field := range sortedKeys(f.MustNot) {
+		values, _ := filterValues(f.MustNot[field])
+		conds = append(conds, map[string]interface{}{"op": "must_not", "field": field, "conds": values})
+	}
+	for _, field := range sortedKeys(f.Range) {
+		r := f.Range[field]
+		cond := map[string]interface{}{"op": "range", "field": field}
+		for key, bound := range map[string]*float64{"gt": r.GT, "gte": r.GTE, "lt": r.LT, "lte": r.LTE} {
+			if bound != nil {
+				cond[key] = *bound
+			}
+		}
+		conds = append(conds, cond)
+	}
+
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	return map[string]interface{}{"op": "and", "conds": conds}
+}
+
+// Matches evaluates the filter against metadata in memory, for backends
+// that cannot filter server-side. Values compare by type: numbers
+// numerically and strings exactly, so 1 never matches "1". A list field
+// matches a condition if any of its items does. An empty filter matches
+// everything.
+func (f *Filter) Matches(meta map[string]interface{}) bool {
+	if f.IsEmpty() {
+		return true
+	}
+	for field, want := range f.Must {
+		if !anyEqual(meta[field], want) {
+			return false
+		}
+	}
+	for field, unwanted := range f.MustNot {
+		if anyEqual(meta[field], unwanted) {
+			return false
+		}
+	}
+	for field, r := range f.Range {
+		value, ok := toFloat(meta[field])
+		if !ok ||
+			r.GT != nil && value <= *r.GT || r.GTE != nil && value < *r.GTE ||
+			r.LT != nil && value >= *r.LT || r.LTE != nil && value > *r.LTE {
+			return false
+		}
+	}
+	return true
+}
+
+// anyEqual reports whether any item of got, a value or a list, equals any of
+// the condition's values.
+func anyEqual(got, want interface{}) bool {
+	wants, err := filterValues(want)
+	if err != nil {
+		return false
+	}
+	items, ok := got.([]interface{})
+	if !ok {
+		items = []interface{}{got}
+	}
+	for _, item := range items {
+		for _, w := range wants {
+			if scalarEqual(item, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func scalarEqual(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	x, ok := a.(string)
+	if !ok {
+		return false
+	}
+	y, ok := b.(string)
+	return ok && x == y
+}
+
+// toFloat converts the number types metadata decodes to.
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// filterValues normalises a condition value to a list of scalars.
+func filterValues(value interface{}) ([]interface{}, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		values = []interface{}{value}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("needs at least one value")
+	}
+	for _, v := range values {
+		switch v.(type) {
+		case string, float64, int, int64:
+		default:
+			return nil, fmt.Errorf("values must be strings or numbers")
+		}
+	}
+	return values, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
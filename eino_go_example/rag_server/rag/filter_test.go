@@ -0,0 +1,181 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFilterDSL(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{name: "nil", filter: `null`, want: `null`},
+		{name: "empty", filter: `{}`, want: `null`},
+		{
+			name:   "single must",
+			filter: `{"must": {"lang": "en"}}`,
+			want:   `{"conds":["en"],"field":"lang","op":"must"}`,
+		},
+		{
+			name:   "must list",
+			filter: `{"must": {"tag": ["a", "b"]}}`,
+			want:   `{"conds":["a","b"],"field":"tag","op":"must"}`,
+		},
+		{
+			name:   "range",
+			filter: `{"range": {"year": {"gte": 2020, "lt": 2024}}}`,
+			want:   `{"field":"year","gte":2020,"lt":2024,"op":"range"}`,
+		},
+		{
+			name:   "conditions sorted by clause then field",
+			filter: `{"range": {"year": {"gt": 2020}}, "must_not": {"lang": "fr"}, "must": {"tag": "go", "author": "ann"}}`,
+			want: `{"conds":[` +
+				`{"conds":["ann"],"field":"author","op":"must"},` +
+				`{"conds":["go"],"field":"tag","op":"must"},` +
+				`{"conds":["fr"],"field":"lang","op":"must_not"},` +
+				`{"field":"year","gt":2020,"op":"range"}` +
+				`],"op":"and"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter *Filter
+			if err := json.Unmarshal([]byte(tt.filter), &filter); err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(filter.DSL())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("DSL = %s\nwant  %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		wantErr bool
+	}{
+		{name: "scalars", filter: `{"must": {"lang": "en", "year": 2024}, "must_not": {"tag": ["a", 1]}}`},
+		{name: "bounded range", filter: `{"range": {"year": {"lte": 2024}}}`},
+		{name: "empty list", filter: `{"must": {"tag": []}}`, wantErr: true},
+		{name: "object value", filter: `{"must_not": {"tag": {"a": 1}}}`, wantErr: true},
+		{name: "bool value", filter: `{"must": {"draft": true}}`, wantErr: true},
+		{name: "nested list", filter: `{"must": {"tag": [["a"]]}}`, wantErr: true},
+		{name: "open range", filter: `{"range": {"year": {}}}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter Filter
+			if err := json.Unmarshal([]byte(tt.filter), &filter); err != nil {
+				t.Fatal(err)
+			}
+			if err := filter.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	meta := map[string]interface{}{
+		"source": "website",
+		"year":   float64(2022),
+		"tags":   []interface{}{"faq", "policy"},
+		"count":  json.Number("3"),
+		"code":   "1",
+	}
+	tests := []struct {
+		name   string
+		filter string
+		want   bool
+	}{
+		{name: "empty", filter: `{}`, want: true},
+		{name: "eq", filter: `{"must": {"source": "website"}}`, want: true},
+		{name: "eq mismatch", filter: `{"must": {"source": "upload"}}`, want: false},
+		{name: "in", filter: `{"must": {"source": ["upload", "website"]}}`, want: true},
+		{name: "number", filter: `{"must": {"year": 2022}}`, want: true},
+		{name: "json number", filter: `{"must": {"count": 3}}`, want: true},
+		{name: "number is not a string", filter: `{"must": {"year": "2022"}}`, want: false},
+		{name: "string is not a number", filter: `{"must": {"code": 1}}`, want: false},
+		{name: "list field contains", filter: `{"must": {"tags": "policy"}}`, want: true},
+		{name: "list field lacks", filter: `{"must": {"tags": "draft"}}`, want: false},
+		{name: "missing field", filter: `{"must": {"tenant": "acme"}}`, want: false},
+		{name: "must_not", filter: `{"must_not": {"tags": ["draft", "faq"]}}`, want: false},
+		{name: "must_not missing field", filter: `{"must_not": {"tenant": "acme"}}`, want: true},
+		{name: "range", filter: `{"range": {"year": {"gte": 2020, "lt": 2023}}}`, want: true},
+		{name: "range excludes bound", filter: `{"range": {"year": {"gt": 2022}}}`, want: false},
+		{name: "range on a string", filter: `{"range": {"source": {"gt": 0}}}`, want: false},
+		{name: "all conditions", filter: `{"must": {"source": "website"}, "must_not": {"tags": "draft"}, "range": {"year": {"lte": 2022}}}`, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter *Filter
+			if err := json.Unmarshal([]byte(tt.filter), &filter); err != nil {
+				t.Fatal(err)
+			}
+			if got := filter.Matches(meta); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// deletingBackend records the delete requests it receives.
+type deletingBackend struct {
+	stubBackend
+	requests []*DeleteDocumentsRequest
+}
+
+func (b *deletingBackend) DeleteDocuments(ctx context.Context, req *DeleteDocumentsRequest) (*DeleteDocumentsResult, error) {
+	b.requests = append(b.requests, req)
+	return &DeleteDocumentsResult{DryRun: req.DryRun, Deleted: []string{}}, nil
+}
+
+func TestBulkDeleteFilter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "filter", body: `{"filter": {"must": {"source": "website"}, "range": {"year": {"lt": 2023}}}, "dry_run": true}`, want: http.StatusOK},
+		{name: "ids", body: `{"ids": ["doc_1"]}`, want: http.StatusOK},
+		{name: "nothing selected", body: `{"dry_run": true}`, want: http.StatusBadRequest},
+		{name: "empty filter", body: `{"filter": {}}`, want: http.StatusBadRequest},
+		{name: "invalid filter", body: `{"filter": {"must": {"draft": true}}}`, want: http.StatusBadRequest},
+		{name: "open range", body: `{"ids": ["doc_1"], "filter": {"range": {"year": {}}}}`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &deletingBackend{stubBackend: stubBackend{name: "local"}}
+			router := NewRouter(NewService(backend, nil, &Config{}))
+			req := httptest.NewRequest(http.MethodPost, "/documents/delete", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.want, w.Body)
+			}
+			wantCalls := 0
+			if tt.want == http.StatusOK {
+				wantCalls = 1
+			}
+			if len(backend.requests) != wantCalls {
+				t.Errorf("backend called %d times, want %d", len(backend.requests), wantCalls)
+			}
+		})
+	}
+}
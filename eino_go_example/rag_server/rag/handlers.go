@@ -0,0 +1,314 @@
+package rag
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/kbhttp"
+)
+
+// HTTP Handlers
+func (s *Service) Query(c *gin.Context) {
+	var req QueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := req.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Prepend any stored history for this conversation
+	conversationID := req.ConversationID
+	turns := append([]Message(nil), req.Messages...)
+	if conversationID == "" {
+		conversationID = newConversationID()
+	} else {
+		req.Messages = append(s.conversations.History(conversationID), req.Messages...)
+	}
+	turns = append(turns, Message{Role: "user", Content: req.Query})
+
+	// Check if we should use RAG (generate answer) or just retrieve documents
+	useRAG := c.Query("rag") == "true"
+	if useRAG && s.chatModel == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Answer generation is disabled",
+			"message": "The server runs retrieval-only; configure CHAT_PROVIDER to enable rag=true",
+		})
+		return
+	}
+
+	if useRAG && wantsStream(c) {
+		s.streamRAG(c, &req, conversationID, turns)
+		return
+	}
+
+	retrieval, err := s.Retrieve(c.Request.Context(), req.Query, &req.SearchOptions)
+	if err != nil {
+		log.Printf("Query documents failed: %v", err)
+		c.JSON(searchErrorStatus(err), gin.H{"error": fmt.Sprintf("Failed to query documents: %v", err)})
+		return
+	}
+	docs := retrieval.Documents
+
+	response := QueryResponse{
+		Documents:      toDocumentResponses(docs),
+		Count:          len(docs),
+		ConversationID: conversationID,
+		Sources:        retrieval.Sources,
+	}
+
+	if useRAG {
+		// Use RAG to generate answer
+		answer, err := s.Generate(c.Request.Context(), req.Query, docs, &req.SearchOptions)
+		if err != nil {
+			log.Printf("RAG query failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process RAG query", "message": err.Error()})
+			return
+		}
+		response.Answer, response.Citations = resolveCitations(answer, docs)
+		turns = append(turns, Message{Role: "assistant", Content: response.Answer})
+	}
+	s.conversations.Append(conversationID, turns...)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UploadDocument accepts either a JSON body with raw content or a URL, or a
+// multipart form with a "file" field plus optional doc_id, doc_name, doc_type,
+// metadata and chunking (JSON objects) fields. With the job queue started the
+// document is ingested in the background and the response names its job.
+func (s *Service) UploadDocument(c *gin.Context) {
+	adder, ok := s.backend.(DocumentAdder)
+	if !ok {
+		s.notImplemented(c, "Document upload")
+		return
+	}
+
+	doc, ok := bindDocument(c)
+	if !ok {
+		return
+	}
+	if err := s.validateDocument(doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Document upload failed", "message": err.Error()})
+		return
+	}
+
+	if s.jobs != nil {
+		job, err := s.enqueueDocument(doc)
+		if err != nil {
+			log.Printf("Document enqueue failed: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue document", "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, UploadResponse{
+			Message:    "Document queued for ingestion",
+			DocumentID: doc.DocID,
+			JobID:      job.ID,
+			Status:     StatusQueued,
+		})
+		return
+	}
+
+	if !s.parseDocumentOrAbort(c, doc) {
+		return
+	}
+
+	result, err := adder.AddDocument(c.Request.Context(), doc)
+	if err != nil {
+		log.Printf("Document upload failed: %v", err)
+		status := http.StatusBadGateway
+		if errors.Is(err, ErrInvalidDocument) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
+			"error":   "Document upload failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	response := UploadResponse{
+		Message:    "Document uploaded successfully",
+		DocumentID: result.DocID,
+		ChunkIDs:   result.ChunkIDs,
+		Status:     result.Status,
+		Error:      result.Error,
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListDocuments pages through the backend's documents. Pagination uses
+// either offset or the opaque cursor returned as next_cursor; results can be
+// narrowed with doc_type and status (completed, failed, queued, processing).
+func (s *Service) ListDocuments(c *gin.Context) {
+	lister, ok := s.backend.(DocumentLister)
+	if !ok {
+		s.notImplemented(c, "Document listing")
+		return
+	}
+
+	// Parse query parameters
+	limitStr := c.DefaultQuery("limit", "10")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit <= 0 || limit > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit parameter"})
+		return
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset parameter"})
+		return
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		offset, err = DecodeCursor(cursor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor parameter"})
+			return
+		}
+	}
+
+	status := c.Query("status")
+	if status != "" && !isValidStatusName(status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status parameter"})
+		return
+	}
+
+	result, err := lister.ListDocuments(c.Request.Context(), ListDocumentsOptions{
+		Offset:  offset,
+		Limit:   limit,
+		DocType: c.Query("doc_type"),
+		Status:  status,
+	})
+	if err != nil {
+		log.Printf("Failed to list documents: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to list documents",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// DeleteDocument deletes a single document. With ?dry_run=true it only
+// reports whether the document exists and would be removed.
+func (s *Service) DeleteDocument(c *gin.Context) {
+	deleter, ok := s.backend.(DocumentDeleter)
+	if !ok {
+		s.notImplemented(c, "Document deletion")
+		return
+	}
+
+	documentID := c.Param("id")
+	if documentID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Document ID is required"})
+		return
+	}
+
+	result, err := deleter.DeleteDocuments(c.Request.Context(), &DeleteDocumentsRequest{
+		IDs:    []string{documentID},
+		DryRun: c.Query("dry_run") == "true",
+	})
+	if err != nil {
+		log.Printf("Failed to delete document %s: %v", documentID, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to delete document",
+			"message": err.Error(),
+		})
+		return
+	}
+	if len(result.NotFound) > 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found", "document_id": documentID})
+		return
+	}
+	if msg, failed := result.Failed[documentID]; failed {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":       "Failed to delete document",
+			"message":     msg,
+			"document_id": documentID,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// BulkDeleteDocuments deletes every document listed in ids and/or matching
+// the metadata filter. Partial failures are reported per document.
+func (s *Service) BulkDeleteDocuments(c *gin.Context) {
+	deleter, ok := s.backend.(DocumentDeleter)
+	if !ok {
+		s.notImplemented(c, "Document deletion")
+		return
+	}
+
+	var req DeleteDocumentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.IDs) == 0 && req.Filter.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "either ids or filter is required"})
+		return
+	}
+	if err := req.Filter.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := deleter.DeleteDocuments(c.Request.Context(), &req)
+	if err != nil {
+		log.Printf("Bulk document deletion failed: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to delete documents",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Service) notImplemented(c *gin.Context, feature string) {
+	c.JSON(http.StatusNotImplemented, gin.H{
+		"error":   feature + " not implemented",
+		"message": fmt.Sprintf("The %s backend does not support this operation", s.backend.Name()),
+	})
+}
+
+// searchErrorStatus maps a search failure to a status code: options the
+// backend rejects or requires are the client's fault, an open circuit breaker means the
+// knowledge base is down, anything else is ours.
+func searchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrUnsupportedOption), errors.Is(err, ErrMissingOption),
+		errors.Is(err, ErrUnknownCollection), errors.Is(err, ErrUnknownBackend):
+		return http.StatusBadRequest
+	case errors.Is(err, kbhttp.ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func isValidStatusName(status string) bool {
+	switch status {
+	case StatusCompleted, StatusFailed, StatusQueued, StatusProcessing:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,377 @@
+package rag_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/chatmodel"
+	"rag-backend/docparse"
+	"rag-backend/kbauth"
+	"rag-backend/kbhttp"
+	"rag-backend/rag"
+	"rag-backend/ragkb"
+	"rag-backend/ragkb/ragkbtest"
+)
+
+var testSigner = &kbauth.Signer{
+	AccessKey: "test-ak",
+	SecretKey: "test-sk",
+	Region:    "cn-beijing",
+	AccountID: "2100000000",
+}
+
+var testCorpus = []ragkbtest.Chunk{
+	{DocName: "channels.md", Content: "Channels connect goroutines. A channel send blocks until a receiver is ready."},
+	{DocName: "maps.md", Content: "Maps are hash tables. Iteration order over a map is randomized."},
+	{DocName: "goroutines.md", Content: "Goroutines are lightweight threads that communicate over a channel."},
+}
+
+// harness serves the handlers against a fake ragKB holding testCorpus.
+type harness struct {
+	fake   *ragkbtest.Server
+	router *gin.Engine
+}
+
+type harnessOptions struct {
+	// signer signs the backend's requests; the fake always verifies with
+	// testSigner
+	signer    *kbauth.Signer
+	http      kbhttp.Config
+	chatModel model.BaseChatModel
+}
+
+func newHarness(t *testing.T, opts harnessOptions) *harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	fake := ragkbtest.NewServer(testSigner, "docs")
+	t.Cleanup(fake.Close)
+	fake.SetCorpus(testCorpus)
+
+	config := fake.Config()
+	if opts.signer != nil {
+		config.AccessKey = opts.signer.AccessKey
+		config.SecretKey = opts.signer.SecretKey
+		config.Region = opts.signer.Region
+	}
+	if opts.http.Timeout == 0 {
+		opts.http = kbhttp.Config{Timeout: 2 * time.Second}
+	}
+	config.HTTPClient = kbhttp.New(opts.http)
+	backend, err := ragkb.New(config)
+	if err != nil {
+		t.Fatalf("ragkb.New: %v", err)
+	}
+
+	service := rag.NewService(backend, opts.chatModel, &rag.Config{})
+	router := rag.NewRouter(service)
+	router.GET("/api/collections", backend.ListCollectionsHandler)
+	return &harness{fake: fake, router: router}
+}
+
+func (h *harness) do(t *testing.T, method, path string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.router.ServeHTTP(w, req)
+	return w
+}
+
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+func TestQueryRanksWithBM25(t *testing.T) {
+	h := newHarness(t, harnessOptions{})
+
+	w := h.do(t, http.MethodPost, "/query", rag.QueryRequest{Query: "channel receiver"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	response := decode[rag.QueryResponse](t, w)
+
+	var names []string
+	for _, doc := range response.Documents {
+		names = append(names, doc.Metadata["doc_name"].(string))
+	}
+	if got, want := strings.Join(names, ","), "channels.md,goroutines.md"; got != want {
+		t.Errorf("documents = %s, want %s", got, want)
+	}
+	for i := 1; i < len(response.Documents); i++ {
+		if response.Documents[i].Score > response.Documents[i-1].Score {
+			t.Errorf("document %d scores %v, above document %d at %v", i, response.Documents[i].Score, i-1, response.Documents[i-1].Score)
+		}
+	}
+	if response.ConversationID == "" {
+		t.Error("no conversation ID issued")
+	}
+	if got := len(h.fake.Searches()); got != 1 {
+		t.Errorf("fake received %d searches, want 1", got)
+	}
+}
+
+func TestQueryRejectsBadSignature(t *testing.T) {
+	wrong := *testSigner
+	wrong.SecretKey = "wrong-sk"
+	h := newHarness(t, harnessOptions{signer: &wrong})
+
+	w := h.do(t, http.MethodPost, "/query", rag.QueryRequest{Query: "channel"})
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d, body %s", w.Code, http.StatusInternalServerError, w.Body)
+	}
+	if !strings.Contains(w.Body.String(), "status 401") {
+		t.Errorf("body %s does not report the rejected signature", w.Body)
+	}
+	if got := h.fake.AuthFailures(); got != 1 {
+		t.Errorf("fake rejected %d requests, want 1", got)
+	}
+	if got := len(h.fake.Searches()); got != 0 {
+		t.Errorf("fake ran %d searches for unsigned requests", got)
+	}
+}
+
+func TestQueryUpstreamFaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		fault    ragkbtest.Fault
+		wantBody string
+	}{
+		{
+			name:     "error code",
+			fault:    ragkbtest.Fault{Code: 1000001, Message: "quota exceeded"},
+			wantBody: "code 1000001",
+		},
+		{
+			name:     "server error",
+			fault:    ragkbtest.Fault{Status: http.StatusServiceUnavailable, Message: "overloaded"},
+			wantBody: "status 503",
+		},
+		{
+			name:     "rate limited",
+			fault:    ragkbtest.Fault{Status: http.StatusTooManyRequests, Message: "slow down"},
+			wantBody: "status 429",
+		},
+		{
+			name:     "latency past timeout",
+			fault:    ragkbtest.Fault{Latency: time.Second},
+			wantBody: "Timeout",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHarness(t, harnessOptions{http: kbhttp.Config{Timeout: 100 * time.Millisecond}})
+			h.fake.SetFault(ragkbtest.SearchKnowledgePath, tt.fault)
+
+			w := h.do(t, http.MethodPost, "/query", rag.QueryRequest{Query: "channel"})
+			if w.Code != http.StatusInternalServerError {
+				t.Errorf("status = %d, want %d, body %s", w.Code, http.StatusInternalServerError, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("body %s does not contain %q", w.Body, tt.wantBody)
+			}
+
+			h.fake.ClearFaults()
+			if w := h.do(t, http.MethodPost, "/query", rag.QueryRequest{Query: "channel"}); w.Code != http.StatusOK {
+				t.Errorf("status after clearing the fault = %d, body %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestQueryLatencyWithinTimeout(t *testing.T) {
+	h := newHarness(t, harnessOptions{http: kbhttp.Config{Timeout: time.Second}})
+	h.fake.SetFault(ragkbtest.SearchKnowledgePath, ragkbtest.Fault{Latency: 50 * time.Millisecond})
+
+	start := time.Now()
+	w := h.do(t, http.MethodPost, "/query", rag.QueryRequest{Query: "channel"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("query took %s, the fault's latency was not applied", elapsed)
+	}
+}
+
+func TestQueryOpenBreaker(t *testing.T) {
+	h := newHarness(t, harnessOptions{http: kbhttp.Config{
+		Timeout:          time.Second,
+		BreakerThreshold: 1,
+		BreakerCooldown:  time.Minute,
+	}})
+	h.fake.SetFault(ragkbtest.SearchKnowledgePath, ragkbtest.Fault{Status: http.StatusBadGateway})
+
+	if w := h.do(t, http.MethodPost, "/query", rag.QueryRequest{Query: "channel"}); w.Code != http.StatusInternalServerError {
+		t.Errorf("first status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	searches := len(h.fake.Searches())
+	if w := h.do(t, http.MethodPost, "/query", rag.QueryRequest{Query: "channel"}); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status with the breaker open = %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if got := len(h.fake.Searches()); got != searches {
+		t.Errorf("fake received a search while the breaker was open")
+	}
+}
+
+func TestQueryBadRequests(t *testing.T) {
+	topK := 0
+	tests := []struct {
+		name string
+		body interface{}
+	}{
+		{name: "no query", body: map[string]string{}},
+		{name: "top_k out of range", body: rag.QueryRequest{Query: "channel", SearchOptions: rag.SearchOptions{TopK: &topK}}},
+		{name: "unknown collection", body: rag.QueryRequest{Query: "channel", SearchOptions: rag.SearchOptions{Collection: "missing"}}},
+		{name: "partition", body: rag.QueryRequest{Query: "channel", SearchOptions: rag.SearchOptions{Partition: "p1"}}},
+	}
+	h := newHarness(t, harnessOptions{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := h.do(t, http.MethodPost, "/query", tt.body); w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d, body %s", w.Code, http.StatusBadRequest, w.Body)
+			}
+		})
+	}
+}
+
+func TestChatCitesRetrievedChunks(t *testing.T) {
+	echo, err := chatmodel.NewEchoModel("Sends block until received [1], see also [9].")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newHarness(t, harnessOptions{chatModel: echo})
+
+	w := h.do(t, http.MethodPost, "/query?rag=true", rag.QueryRequest{Query: "channel receiver"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	response := decode[rag.QueryResponse](t, w)
+	if want := "Sends block until received [1], see also."; response.Answer != want {
+		t.Errorf("answer = %q, want %q", response.Answer, want)
+	}
+	if len(response.Citations) != 1 || response.Citations[0].ChunkID != response.Documents[0].ID {
+		t.Errorf("citations = %+v, want the first document", response.Citations)
+	}
+	if response.Citations[0].DocName != "channels.md" {
+		t.Errorf("cited %q, want channels.md", response.Citations[0].DocName)
+	}
+}
+
+func TestChatStreams(t *testing.T) {
+	echo, err := chatmodel.NewEchoModel("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newHarness(t, harnessOptions{chatModel: echo})
+
+	w := h.do(t, http.MethodPost, "/query?rag=true&stream=true", rag.QueryRequest{Query: "channel"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	body := w.Body.String()
+	for _, event := range []string{"event:documents", "event:token", "event:done"} {
+		if !strings.Contains(body, event) {
+			t.Errorf("stream has no %s event: %s", event, body)
+		}
+	}
+}
+
+func TestChatRetrievalOnly(t *testing.T) {
+	h := newHarness(t, harnessOptions{})
+
+	w := h.do(t, http.MethodPost, "/query?rag=true", rag.QueryRequest{Query: "channel"})
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d, body %s", w.Code, http.StatusServiceUnavailable, w.Body)
+	}
+	if got := len(h.fake.Searches()); got != 0 {
+		t.Errorf("fake received %d searches for a rejected query", got)
+	}
+}
+
+func TestListCollections(t *testing.T) {
+	h := newHarness(t, harnessOptions{})
+	h.fake.SetCollection("archive", nil)
+
+	w := h.do(t, http.MethodGet, "/api/collections", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	response := decode[ragkb.ListCollectionsResponse](t, w)
+	var names []string
+	for _, collection := range response.Data.CollectionList {
+		names = append(names, collection.CollectionName)
+	}
+	if got, want := strings.Join(names, ","), "archive,docs"; got != want {
+		t.Errorf("collections = %s, want %s", got, want)
+	}
+
+	h.fake.SetFault(ragkbtest.ListCollectionsPath, ragkbtest.Fault{Status: http.StatusForbidden})
+	if w := h.do(t, http.MethodGet, "/api/collections", nil); w.Code != http.StatusInternalServerError {
+		t.Errorf("status with a failing upstream = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestUploadTooLarge(t *testing.T) {
+	h := newHarness(t, harnessOptions{})
+	large := strings.Repeat("a", docparse.MaxFetchSize)
+
+	var form bytes.Buffer
+	writer := multipart.NewWriter(&form)
+	part, err := writer.CreateFormFile("file", "large.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(large))
+	writer.Close()
+
+	var small bytes.Buffer
+	smallWriter := multipart.NewWriter(&small)
+	smallWriter.WriteField("doc_name", "empty.txt")
+	smallWriter.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        int
+	}{
+		{name: "json", contentType: "application/json", body: []byte(`{"content": "` + large + `"}`), want: http.StatusRequestEntityTooLarge},
+		{name: "multipart", contentType: writer.FormDataContentType(), body: form.Bytes(), want: http.StatusRequestEntityTooLarge},
+		{name: "multipart without file", contentType: smallWriter.FormDataContentType(), body: small.Bytes(), want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/documents", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+			h.router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package rag
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/jobs"
+)
+
+// StartJobs opens the job store in config and starts its workers, after
+// which uploads are queued and ingested in the background. The stages and
+// processor are the service's own.
+func (s *Service) StartJobs(ctx context.Context, config jobs.Config) error {
+	if _, ok := s.backend.(DocumentAdder); !ok {
+		return fmt.Errorf("the %s backend cannot ingest documents", s.backend.Name())
+	}
+	config.Stages = IngestStages
+	config.Process = s.processJob
+	queue, err := jobs.Open(config)
+	if err != nil {
+		return err
+	}
+	queue.Start(ctx)
+	s.jobs = queue
+	return nil
+}
+
+// enqueueDocument queues doc for ingestion. Sections are not stored; the
+// job parses the document again.
+func (s *Service) enqueueDocument(doc *AddDocumentRequest) (*jobs.Job, error) {
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode document: %w", err)
+	}
+	name := doc.DocName
+	if name == "" {
+		name = doc.URL
+	}
+	return s.jobs.Enqueue(name, payload)
+}
+
+// processJob parses and adds a queued document, reporting each stage to
+// the job.
+func (s *Service) processJob(ctx context.Context, payload []byte, report jobs.ReportFunc) ([]string, error) {
+	var doc AddDocumentRequest
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode document: %w", err)
+	}
+	ctx = WithProgress(ctx, ProgressFunc(report))
+
+	if err := s.parseDocument(ctx, &doc); err != nil {
+		return nil, err
+	}
+	result, err := s.backend.(DocumentAdder).AddDocument(ctx, &doc)
+	if err != nil {
+		return nil, err
+	}
+	if result.DocID == "" {
+		return nil, nil
+	}
+	return []string{result.DocID}, nil
+}
+
+// GetJob reports the state of an ingestion job and each of its stages.
+func (s *Service) GetJob(c *gin.Context) {
+	if !s.jobsEnabled(c) {
+		return
+	}
+	job, err := s.jobs.Get(c.Param("id"))
+	s.jobResponse(c, job, err)
+}
+
+// CancelJob stops a queued or running job.
+func (s *Service) CancelJob(c *gin.Context) {
+	if !s.jobsEnabled(c) {
+		return
+	}
+	job, err := s.jobs.Cancel(c.Param("id"))
+	s.jobResponse(c, job, err)
+}
+
+// RetryJob queues a failed or canceled job again.
+func (s *Service) RetryJob(c *gin.Context) {
+	if !s.jobsEnabled(c) {
+		return
+	}
+	job, err := s.jobs.Retry(c.Param("id"))
+	s.jobResponse(c, job, err)
+}
+
+func (s *Service) jobsEnabled(c *gin.Context) bool {
+	if s.jobs != nil {
+		return true
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error":   "Ingestion jobs are disabled",
+		"message": "Uploads are ingested synchronously; set JOBS_ENABLED to queue them",
+	})
+	return false
+}
+
+func (s *Service) jobResponse(c *gin.Context, job *jobs.Job, err error) {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	case errors.Is(err, jobs.ErrInvalidState):
+		c.JSON(http.StatusConflict, gin.H{"error": "Job cannot be changed", "message": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access job", "message": err.Error()})
+	default:
+		c.JSON(http.StatusOK, job)
+	}
+}
@@ -0,0 +1,81 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/jobs"
+)
+
+// addingBackend is a chunkingBackend that accepts every document.
+type addingBackend struct {
+	chunkingBackend
+}
+
+func (b *addingBackend) AddDocument(ctx context.Context, doc *AddDocumentRequest) (*AddDocumentResult, error) {
+	return &AddDocumentResult{DocID: doc.DocID, Status: StatusCompleted}, nil
+}
+
+func TestQueuedUploadValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := NewService(&addingBackend{chunkingBackend{stubBackend: stubBackend{name: "local"}}}, nil, &Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := service.StartJobs(ctx, jobs.Config{Path: filepath.Join(t.TempDir(), "jobs.db")}); err != nil {
+		t.Fatal(err)
+	}
+	defer service.jobs.Close()
+	router := NewRouter(service)
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<p>Guide</p>"))
+	}))
+	defer page.Close()
+
+	tests := []struct {
+		name     string
+		fileName string
+		file     string
+		json     string
+		want     int
+	}{
+		{name: "text file", fileName: "notes.txt", file: "Some notes.", want: http.StatusAccepted},
+		{name: "json content", json: `{"content": "Some notes.", "doc_name": "notes.md"}`, want: http.StatusAccepted},
+		{name: "json url", json: `{"url": "` + page.URL + `/guide.html"}`, want: http.StatusAccepted},
+		{name: "binary file", fileName: "image.png", file: "\x89PNG\r\n\x1a\n\xff\xfe", want: http.StatusBadRequest},
+		{name: "empty file", fileName: "empty.txt", file: " \n", want: http.StatusBadRequest},
+		{name: "not an http url", json: `{"url": "file:///etc/passwd"}`, want: http.StatusBadRequest},
+		{name: "invalid chunking", json: `{"content": "Some notes.", "chunking": {"chunk_size": 50, "overlap": 60}}`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			if tt.json != "" {
+				req = httptest.NewRequest(http.MethodPost, "/documents", bytes.NewReader([]byte(tt.json)))
+				req.Header.Set("Content-Type", "application/json")
+			} else {
+				var form bytes.Buffer
+				writer := multipart.NewWriter(&form)
+				part, err := writer.CreateFormFile("file", tt.fileName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				part.Write([]byte(tt.file))
+				writer.Close()
+				req = httptest.NewRequest(http.MethodPost, "/documents", &form)
+				req.Header.Set("Content-Type", writer.FormDataContentType())
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d, body %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package rag
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"path/filepath"
+
+	"rag-backend/docparse"
+)
+
+// parseDocument fills doc.Sections unless the backend parses uploads itself,
+// downloading a URL document first. Files that cannot be parsed are the
+// client's fault.
+func (s *Service) parseDocument(ctx context.Context, doc *AddDocumentRequest) error {
+	if remote, ok := s.backend.(RemoteParser); ok && remote.ParsesDocuments() {
+		return nil
+	}
+
+	ReportProgress(ctx, StageParse, 0, 1)
+	if doc.URL != "" {
+		content, contentType, err := docparse.Fetch(ctx, doc.URL)
+		if err != nil {
+			return err
+		}
+		doc.Content = content
+		if doc.ContentType == "" {
+			doc.ContentType = contentType
+		}
+		if doc.DocName == "" {
+			if u, err := url.Parse(doc.URL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+				doc.DocName = path.Base(u.Path)
+			} else {
+				doc.DocName = doc.URL
+			}
+		}
+	}
+
+	name := parseName(doc)
+	sections, err := docparse.Parse(ctx, name, doc.ContentType, doc.Content, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidDocument, err)
+	}
+	if len(sections) == 0 {
+		return fmt.Errorf("%w: no text found in %s", ErrInvalidDocument, name)
+	}
+
+	doc.Sections = sections
+	ReportProgress(ctx, StageParse, 1, 1)
+	log.Printf("Parsed %s into %d sections", name, len(sections))
+	return nil
+}
+
+// validateDocument checks what can be checked of an upload without
+// downloading or parsing it, so that a queued upload fails with the same
+// client errors as one ingested during the request: the chunking overrides,
+// the URL, and the type and presence of the content.
+func (s *Service) validateDocument(doc *AddDocumentRequest) error {
+	if backend, ok := s.backend.(ChunkingBackend); ok {
+		if err := backend.ChunkingConfig().Merge(doc.Chunking).Validate(); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidDocument, err)
+		}
+	}
+	if doc.URL == "" && len(bytes.TrimSpace(doc.Content)) == 0 {
+		return fmt.Errorf("%w: no text found in %s", ErrInvalidDocument, parseName(doc))
+	}
+	if remote, ok := s.backend.(RemoteParser); ok && remote.ParsesDocuments() {
+		return nil
+	}
+
+	if doc.URL != "" {
+		if err := docparse.CheckURL(doc.URL); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidDocument, err)
+		}
+		return nil
+	}
+	if _, err := docparse.DetectType(parseName(doc), doc.ContentType, doc.Content); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDocument, err)
+	}
+	return nil
+}
+
+// parseName is the file name a document is parsed as. doc_type names the
+// format when the file name does not.
+func parseName(doc *AddDocumentRequest) string {
+	name := doc.DocName
+	if doc.DocType != "" && filepath.Ext(name) == "" {
+		name += "." + doc.DocType
+	}
+	return name
+}
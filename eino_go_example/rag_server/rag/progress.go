@@ -0,0 +1,33 @@
+package rag
+
+import "context"
+
+// Ingestion stages, in order. Backends report the ones they perform with
+// ReportProgress.
+const (
+	StageParse  = "parse"
+	StageChunk  = "chunk"
+	StageEmbed  = "embed"
+	StageUpsert = "upsert"
+)
+
+// IngestStages lists the ingestion stages in order.
+var IngestStages = []string{StageParse, StageChunk, StageEmbed, StageUpsert}
+
+// ProgressFunc receives how much of an ingestion stage is done.
+type ProgressFunc func(stage string, done, total int)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose ingestion progress is reported to fn.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// ReportProgress reports that done of total units of an ingestion stage
+// are finished, if ctx was given a ProgressFunc.
+func ReportProgress(ctx context.Context, stage string, done, total int) {
+	if fn, ok := ctx.Value(progressKey{}).(ProgressFunc); ok {
+		fn(stage, done, total)
+	}
+}
@@ -0,0 +1,52 @@
+package rag
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes mounts the query and document endpoints on group.
+func (s *Service) RegisterRoutes(group gin.IRoutes) {
+	group.POST("/query", s.Query)
+	group.POST("/documents", s.UploadDocument)
+	group.POST("/documents/preview-chunks", s.PreviewChunks)
+	group.GET("/documents", s.ListDocuments)
+	group.DELETE("/documents/:id", s.DeleteDocument)
+	group.POST("/documents/delete", s.BulkDeleteDocuments)
+	group.GET("/jobs/:id", s.GetJob)
+	group.POST("/jobs/:id/cancel", s.CancelJob)
+	group.POST("/jobs/:id/retry", s.RetryJob)
+}
+
+// NewRouter returns an engine with CORS, a health check and the service
+// routes mounted both at the root and under /api/v1.
+func NewRouter(s *Service) *gin.Engine {
+	router := gin.Default()
+	router.Use(CORSMiddleware())
+
+	// Health check endpoint
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "healthy", "backend": s.backend.Name()})
+	})
+
+	s.RegisterRoutes(router)
+	s.RegisterRoutes(router.Group("/api/v1"))
+
+	return router
+}
+
+func CORSMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Access-Control-Allow-Origin", "*")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
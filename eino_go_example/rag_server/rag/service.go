@@ -0,0 +1,252 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudwego/eino/components/model"
+	"github.com/cloudwego/eino/schema"
+
+	"rag-backend/jobs"
+)
+
+// ErrNoChatModel is returned for answer generation when the service runs
+// retrieval-only.
+var ErrNoChatModel = errors.New("no chat model configured")
+
+// Service answers queries against a Backend, optionally generating an answer
+// with the chat model. Without a chat model only retrieval is available.
+// Federated queries may also search the extra backends.
+type Service struct {
+	backend       Backend
+	backends      map[string]Backend
+	chatModel     model.BaseChatModel
+	conversations *ConversationStore
+	maxImages     int
+	// jobs queues uploads for background ingestion; nil ingests them
+	// during the request. See StartJobs.
+	jobs *jobs.Queue
+}
+
+type Config struct {
+	// Conversation history kept for multi-turn queries
+	ConversationTTL         time.Duration
+	ConversationMaxMessages int
+	// MaxImages is how many image attachments of the retrieved chunks are
+	// passed to the chat model with the question; zero sends text only. Only
+	// set it for multimodal models.
+	MaxImages int
+	// ExtraBackends can be named as sources of federated queries alongside
+	// the primary backend.
+	ExtraBackends []Backend
+}
+
+func NewService(backend Backend, chatModel model.BaseChatModel, config *Config) *Service {
+	backends := map[string]Backend{backend.Name(): backend}
+	for _, extra := range config.ExtraBackends {
+		backends[extra.Name()] = extra
+	}
+	return &Service{
+		backend:       backend,
+		backends:      backends,
+		chatModel:     chatModel,
+		conversations: NewConversationStore(config.ConversationTTL, config.ConversationMaxMessages),
+		maxImages:     config.MaxImages,
+	}
+}
+
+// Backend returns the backend queries are answered from.
+func (s *Service) Backend() Backend {
+	return s.backend
+}
+
+// Core retrieval methods
+func (s *Service) QueryDocuments(ctx context.Context, query string, opts *SearchOptions) ([]*schema.Document, error) {
+	retrieval, err := s.Retrieve(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	return retrieval.Documents, nil
+}
+
+// Retrieve searches the primary backend, or every source of a federated
+// query, and applies min_score to the backends' scores.
+func (s *Service) Retrieve(ctx context.Context, query string, opts *SearchOptions) (*Retrieval, error) {
+	if opts == nil {
+		opts = &SearchOptions{}
+	}
+
+	var retrieval *Retrieval
+	if opts.federated() {
+		var err error
+		if retrieval, err = s.federatedSearch(ctx, query, opts); err != nil {
+			return nil, fmt.Errorf("federated search failed: %w", err)
+		}
+	} else {
+		docs, err := s.backend.Search(ctx, query, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s search failed: %w", s.backend.Name(), err)
+		}
+		if opts.MinScore != nil {
+			docs = filterByScore(docs, *opts.MinScore)
+		}
+		retrieval = &Retrieval{Documents: docs}
+	}
+	return retrieval, nil
+}
+
+// filterByScore keeps the documents scoring at least minScore, in order.
+func filterByScore(docs []*schema.Document, minScore float64) []*schema.Document {
+	kept := docs[:0]
+	for _, doc := range docs {
+		if doc.Score() >= minScore {
+			kept = append(kept, doc)
+		}
+	}
+	return kept
+}
+
+// RAG with chat model
+func (s *Service) QueryWithRAG(ctx context.Context, query string, opts *SearchOptions) (string, []*schema.Document, error) {
+	if s.chatModel == nil {
+		return "", nil, ErrNoChatModel
+	}
+
+	// First, retrieve relevant documents
+	docs, err := s.QueryDocuments(ctx, query, opts)
+	if err != nil {
+		return "", nil, fmt.Errorf("document retrieval failed: %w", err)
+	}
+
+	answer, err := s.Generate(ctx, query, docs, opts)
+	return answer, docs, err
+}
+
+// QueryWithRAGStream retrieves documents like QueryWithRAG but returns the
+// answer as a stream of message chunks. The caller must close the stream.
+func (s *Service) QueryWithRAGStream(ctx context.Context, query string, opts *SearchOptions) (*schema.StreamReader[*schema.Message], []*schema.Document, error) {
+	if s.chatModel == nil {
+		return nil, nil, ErrNoChatModel
+	}
+
+	docs, err := s.QueryDocuments(ctx, query, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("document retrieval failed: %w", err)
+	}
+
+	stream, err := s.GenerateStream(ctx, query, docs, opts)
+	return stream, docs, err
+}
+
+// Generate answers query from already retrieved documents.
+func (s *Service) Generate(ctx context.Context, query string, docs []*schema.Document, opts *SearchOptions) (string, error) {
+	if s.chatModel == nil {
+		return "", ErrNoChatModel
+	}
+
+	response, err := s.chatModel.Generate(ctx, s.buildRAGMessages(query, docs, opts))
+	if err != nil {
+		return "", fmt.Errorf("chat model generation failed: %w", err)
+	}
+	return response.Content, nil
+}
+
+// GenerateStream is Generate returning the answer as a stream of message
+// chunks. The caller must close the stream.
+func (s *Service) GenerateStream(ctx context.Context, query string, docs []*schema.Document, opts *SearchOptions) (*schema.StreamReader[*schema.Message], error) {
+	if s.chatModel == nil {
+		return nil, ErrNoChatModel
+	}
+
+	stream, err := s.chatModel.Stream(ctx, s.buildRAGMessages(query, docs, opts))
+	if err != nil {
+		return nil, fmt.Errorf("chat model stream failed: %w", err)
+	}
+	return stream, nil
+}
+
+// buildRAGMessages builds the chat model input from the retrieved documents,
+// numbered so the answer can cite them, any earlier conversation turns, and
+// the question.
+func (s *Service) buildRAGMessages(query string, docs []*schema.Document, opts *SearchOptions) []*schema.Message {
+	// Create prompt with context and query
+	prompt := fmt.Sprintf(`Based on the following context, please answer the question.
+
+Context:
+%s
+
+Question: %s
+
+Answer:`, formatNumberedContext(docs), query)
+
+	// Replay earlier turns so the model can resolve references in the question
+	messages := []*schema.Message{
+		schema.SystemMessage(citationInstructions),
+	}
+	if opts != nil {
+		messages = append(messages, HistoryMessages(opts.Messages)...)
+	}
+
+	question := schema.UserMessage(prompt)
+	if images := imageLinks(docs, s.maxImages); len(images) > 0 {
+		// Content stays set for models that ignore MultiContent
+		question.MultiContent = []schema.ChatMessagePart{{Type: schema.ChatMessagePartTypeText, Text: prompt}}
+		for _, link := range images {
+			question.MultiContent = append(question.MultiContent, schema.ChatMessagePart{
+				Type:     schema.ChatMessagePartTypeImageURL,
+				ImageURL: &schema.ChatMessageImageURL{URL: link},
+			})
+		}
+	}
+	return append(messages, question)
+}
+
+// imageLinks returns the links of up to limit image attachments, in the order
+// of the documents.
+func imageLinks(docs []*schema.Document, limit int) []string {
+	var links []string
+	for _, doc := range docs {
+		attachments, _ := doc.MetaData["attachments"].([]Attachment)
+		for _, attachment := range attachments {
+			if len(links) >= limit {
+				return links
+			}
+			if attachment.Type == "image" && attachment.Link != "" {
+				links = append(links, attachment.Link)
+			}
+		}
+	}
+	return links
+}
+
+// HistoryMessages converts conversation turns into chat model messages.
+func HistoryMessages(history []Message) []*schema.Message {
+	messages := make([]*schema.Message, 0, len(history))
+	for _, msg := range history {
+		if msg.Role == "assistant" {
+			messages = append(messages, schema.AssistantMessage(msg.Content, nil))
+		} else {
+			messages = append(messages, schema.UserMessage(msg.Content))
+		}
+	}
+	return messages
+}
+
+func toDocumentResponses(docs []*schema.Document) []*DocumentResponse {
+	docResponses := make([]*DocumentResponse, len(docs))
+	for i, doc := range docs {
+		docResponses[i] = &DocumentResponse{
+			ID:       doc.ID,
+			Content:  doc.Content,
+			Metadata: doc.MetaData,
+			Score:    doc.Score(),
+		}
+		docResponses[i].DocID, _ = doc.MetaData["doc_id"].(string)
+		docResponses[i].RerankScore, _ = doc.MetaData["rerank_score"].(float64)
+		docResponses[i].DenseScore, _ = doc.MetaData["dense_score"].(float64)
+		docResponses[i].SparseScore, _ = doc.MetaData["sparse_score"].(float64)
+	}
+	return docResponses
+}
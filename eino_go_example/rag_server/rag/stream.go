@@ -0,0 +1,119 @@
+package rag
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+)
+
+// Server-Sent Event names emitted by streaming RAG queries, in order.
+const (
+	sseEventDocuments = "documents"
+	sseEventToken     = "token"
+	sseEventDone      = "done"
+	sseEventError     = "error"
+)
+
+type StreamTokenEvent struct {
+	Content string `json:"content"`
+}
+
+// StreamDoneEvent closes a stream. Answer is the full answer with invalid
+// citation markers removed, which may differ from the concatenated tokens.
+type StreamDoneEvent struct {
+	Answer       string             `json:"answer"`
+	Citations    []Citation         `json:"citations,omitempty"`
+	FinishReason string             `json:"finish_reason,omitempty"`
+	Usage        *schema.TokenUsage `json:"usage,omitempty"`
+}
+
+// wantsStream reports whether the client asked for a streamed answer, either
+// with ?stream=true or by accepting text/event-stream.
+func wantsStream(c *gin.Context) bool {
+	return c.Query("stream") == "true" || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+}
+
+// streamRAG answers a RAG query over SSE: a documents event with the
+// retrieved chunks, token events as the answer is generated, then a done
+// event with the validated answer, citations, usage and finish reason.
+// Generation stops when the client disconnects because the model stream
+// shares the request context.
+func (s *Service) streamRAG(c *gin.Context, req *QueryRequest, conversationID string, turns []Message) {
+	ctx := c.Request.Context()
+
+	retrieval, err := s.Retrieve(ctx, req.Query, &req.SearchOptions)
+	if err != nil {
+		log.Printf("RAG stream query failed: %v", err)
+		c.JSON(searchErrorStatus(err), gin.H{"error": "Failed to process RAG query", "message": err.Error()})
+		return
+	}
+	docs := retrieval.Documents
+
+	stream, err := s.GenerateStream(ctx, req.Query, docs, &req.SearchOptions)
+	if err != nil {
+		log.Printf("RAG stream query failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process RAG query", "message": err.Error()})
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	c.SSEvent(sseEventDocuments, QueryResponse{
+		Documents:      toDocumentResponses(docs),
+		Count:          len(docs),
+		ConversationID: conversationID,
+		Sources:        retrieval.Sources,
+	})
+	c.Writer.Flush()
+
+	var (
+		answer strings.Builder
+		done   StreamDoneEvent
+	)
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				log.Printf("RAG stream cancelled by client: %v", ctx.Err())
+				return
+			}
+			log.Printf("RAG stream failed: %v", err)
+			c.SSEvent(sseEventError, gin.H{"error": "Failed to generate answer"})
+			c.Writer.Flush()
+			return
+		}
+
+		if chunk.Content != "" {
+			answer.WriteString(chunk.Content)
+			c.SSEvent(sseEventToken, StreamTokenEvent{Content: chunk.Content})
+			c.Writer.Flush()
+		}
+		if meta := chunk.ResponseMeta; meta != nil {
+			if meta.FinishReason != "" {
+				done.FinishReason = meta.FinishReason
+			}
+			if meta.Usage != nil {
+				done.Usage = meta.Usage
+			}
+		}
+	}
+
+	done.Answer, done.Citations = resolveCitations(answer.String(), docs)
+	s.conversations.Append(conversationID, append(turns, Message{Role: "assistant", Content: done.Answer})...)
+
+	c.SSEvent(sseEventDone, done)
+	c.Writer.Flush()
+}
@@ -0,0 +1,276 @@
+package rag
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	"github.com/cloudwego/eino/schema"
+
+	"rag-backend/chunking"
+)
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// SearchOptions holds per-request overrides of a backend's search defaults.
+// Nil fields fall back to the configured value.
+type SearchOptions struct {
+	TopK                *int     `json:"top_k,omitempty"`
+	DenseWeight         *float64 `json:"dense_weight,omitempty"`
+	Rerank              *bool    `json:"rerank,omitempty"`
+	RerankOnlyChunk     *bool    `json:"rerank_only_chunk,omitempty"`
+	ChunkGroup          *bool    `json:"chunk_group,omitempty"`
+	ChunkDiffusionCount *int     `json:"chunk_diffusion_count,omitempty"`
+	Rewrite             *bool    `json:"rewrite,omitempty"`
+	// UserID scopes memory backends to one user's memories.
+	UserID string `json:"user_id,omitempty"`
+	// Messages is the conversation so far, oldest first, excluding the current
+	// query. When present the query is rewritten against it by default.
+	Messages []Message `json:"messages,omitempty"`
+	// MinScore drops results scoring below it after the backend returns them.
+	// Federated searches apply it to each source's scores before fusing.
+	MinScore *float64 `json:"min_score,omitempty"`
+	// Filter restricts the search to chunks with matching metadata.
+	Filter *Filter `json:"filter,omitempty"`
+	// Partition searches one VikingDB index partition instead of the default.
+	Partition string `json:"partition,omitempty"`
+	// Project and Collections select the ragKB collections to search instead
+	// of the configured one. Collection is shorthand for a single one.
+	Project     string   `json:"project,omitempty"`
+	Collection  string   `json:"collection,omitempty"`
+	Collections []string `json:"collections,omitempty"`
+	// Sources and Fusion ask for a federated search: every source, or every
+	// requested collection when only Fusion is set, is searched concurrently
+	// and the results fused with FusionRRF (the default) or FusionScore.
+	Sources []Source `json:"sources,omitempty"`
+	Fusion  string   `json:"fusion,omitempty"`
+}
+
+// MaxCollections is how many collections one query may search.
+const MaxCollections = 10
+
+// CollectionNames returns the requested collections without duplicates, or
+// nil to search the backend's default.
+func (o *SearchOptions) CollectionNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, name := range append([]string{o.Collection}, o.Collections...) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Validate checks overrides against the ranges accepted by the backends.
+func (o *SearchOptions) Validate() error {
+	if o.TopK != nil && (*o.TopK < 1 || *o.TopK > 200) {
+		return fmt.Errorf("top_k must be between 1 and 200")
+	}
+	if o.DenseWeight != nil && (*o.DenseWeight < 0.2 || *o.DenseWeight > 1) {
+		return fmt.Errorf("dense_weight must be between 0.2 and 1")
+	}
+	if o.ChunkDiffusionCount != nil && (*o.ChunkDiffusionCount < 0 || *o.ChunkDiffusionCount > 5) {
+		return fmt.Errorf("chunk_diffusion_count must be between 0 and 5")
+	}
+	if len(o.CollectionNames())+len(o.Sources) > MaxCollections {
+		return fmt.Errorf("at most %d collections or sources can be searched at once", MaxCollections)
+	}
+	if len(o.Sources) > 0 && (o.Project != "" || len(o.CollectionNames()) > 0) {
+		return fmt.Errorf("sources cannot be combined with project or collections")
+	}
+	if o.Fusion != "" && o.Fusion != FusionRRF && o.Fusion != FusionScore {
+		return fmt.Errorf("fusion must be %s or %s", FusionRRF, FusionScore)
+	}
+	if err := o.Filter.Validate(); err != nil {
+		return err
+	}
+	return ValidateMessages(o.Messages)
+}
+
+// HTTP request/response types
+type QueryRequest struct {
+	Query string `json:"query" binding:"required"`
+	// ConversationID selects server-side history; a new one is issued when empty.
+	ConversationID string `json:"conversation_id,omitempty"`
+	SearchOptions
+}
+
+type QueryResponse struct {
+	Documents      []*DocumentResponse `json:"documents"`
+	Count          int                 `json:"count"`
+	Answer         string              `json:"answer,omitempty"`
+	Citations      []Citation          `json:"citations,omitempty"`
+	ConversationID string              `json:"conversation_id,omitempty"`
+	Sources        []SourceResult      `json:"sources,omitempty"`
+}
+
+// DocumentResponse is one retrieved chunk. ID is stable across queries and
+// DocID names the document it belongs to, when the backend knows it. Score is
+// the backend's overall score; the rerank and dense/sparse component scores
+// are set when the backend reports them.
+type DocumentResponse struct {
+	ID          string                 `json:"id"`
+	DocID       string                 `json:"doc_id,omitempty"`
+	Content     string                 `json:"content"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Score       float64                `json:"score,omitempty"`
+	RerankScore float64                `json:"rerank_score,omitempty"`
+	DenseScore  float64                `json:"dense_score,omitempty"`
+	SparseScore float64                `json:"sparse_score,omitempty"`
+}
+
+// Attachment is a file attached to a chunk, such as an image in a document.
+type Attachment struct {
+	Type    string `json:"type,omitempty"`
+	Link    string `json:"link"`
+	Caption string `json:"caption,omitempty"`
+}
+
+// TableField is one column of a table chunk, which holds one row.
+type TableField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type UploadRequest struct {
+	Content  string                 `json:"content,omitempty"`
+	URL      string                 `json:"url,omitempty"`
+	DocID    string                 `json:"doc_id,omitempty"`
+	DocName  string                 `json:"doc_name,omitempty"`
+	DocType  string                 `json:"doc_type,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// Chunking overrides the backend's chunking settings for this document.
+	Chunking chunking.Config `json:"chunking,omitempty"`
+}
+
+type UploadResponse struct {
+	Message    string   `json:"message"`
+	DocumentID string   `json:"document_id,omitempty"`
+	ChunkIDs   []string `json:"chunk_ids,omitempty"`
+	JobID      string   `json:"job_id,omitempty"`
+	Status     string   `json:"status,omitempty"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// AddDocumentRequest describes a document to add to the knowledge base.
+// Either URL or Content must be set; Content is uploaded as a file named
+// DocName, so raw text and file uploads share the same path.
+type AddDocumentRequest struct {
+	DocID       string                 `json:"doc_id,omitempty"`
+	DocName     string                 `json:"doc_name,omitempty"`
+	DocType     string                 `json:"doc_type,omitempty"`
+	ContentType string                 `json:"content_type,omitempty"`
+	URL         string                 `json:"url,omitempty"`
+	Content     []byte                 `json:"content,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	// Sections is the text of the document as parsed locally, with heading,
+	// page and table metadata; see docparse. It is set for backends that do
+	// not parse uploads themselves.
+	Sections []*schema.Document `json:"-"`
+	// Chunking overrides the settings of backends that chunk documents
+	// locally; see ChunkingBackend.
+	Chunking chunking.Config `json:"chunking,omitempty"`
+}
+
+// AddDocumentResult reports the added document. ChunkIDs lists the chunk
+// primary keys for backends that chunk documents themselves.
+type AddDocumentResult struct {
+	DocID    string   `json:"doc_id"`
+	ChunkIDs []string `json:"chunk_ids,omitempty"`
+	Status   string   `json:"status"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// PreviewChunksResponse lists the chunks a document would be indexed as.
+// Tokens are estimated with chunking.CountTokens.
+type PreviewChunksResponse struct {
+	Chunking    chunking.Config `json:"chunking"`
+	Count       int             `json:"count"`
+	TotalTokens int             `json:"total_tokens"`
+	Chunks      []ChunkPreview  `json:"chunks"`
+}
+
+type ChunkPreview struct {
+	Index    int                    `json:"index"`
+	Content  string                 `json:"content"`
+	Chars    int                    `json:"chars"`
+	Tokens   int                    `json:"tokens"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Document processing states reported by backends
+const (
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+	StatusQueued     = "queued"
+	StatusProcessing = "processing"
+	StatusUnknown    = "unknown"
+)
+
+// ListDocumentsOptions selects a page of documents.
+type ListDocumentsOptions struct {
+	Offset  int
+	Limit   int
+	DocType string
+	Status  string
+}
+
+type DocumentSummary struct {
+	DocID      string `json:"doc_id"`
+	DocName    string `json:"doc_name"`
+	DocType    string `json:"doc_type"`
+	Status     string `json:"status"`
+	CreateTime int64  `json:"create_time"`
+	ChunkCount int    `json:"chunk_count"`
+}
+
+type ListDocumentsResult struct {
+	Documents  []DocumentSummary `json:"documents"`
+	Count      int               `json:"count"`
+	TotalNum   int               `json:"total_num"`
+	NextOffset int               `json:"next_offset,omitempty"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+	HasMore    bool              `json:"has_more"`
+}
+
+// DeleteDocumentsRequest selects documents by ID, by metadata filter, or
+// both. The filter has the same form as a search's. With DryRun set nothing
+// is removed.
+type DeleteDocumentsRequest struct {
+	IDs    []string `json:"ids,omitempty"`
+	Filter *Filter  `json:"filter,omitempty"`
+	DryRun bool     `json:"dry_run,omitempty"`
+}
+
+// DeleteDocumentsResult lists the removed documents. Backends that can tell a
+// missing document from a failed delete report it under NotFound.
+type DeleteDocumentsResult struct {
+	DryRun   bool              `json:"dry_run"`
+	Deleted  []string          `json:"deleted"`
+	NotFound []string          `json:"not_found,omitempty"`
+	Failed   map[string]string `json:"failed,omitempty"`
+	Count    int               `json:"count"`
+}
+
+// EncodeCursor and DecodeCursor wrap an upstream offset in an opaque token so
+// clients do not need to reason about offsets skipped by local filtering.
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+func DecodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	offset, err := strconv.Atoi(string(raw))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return offset, nil
+}
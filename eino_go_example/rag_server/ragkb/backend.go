@@ -0,0 +1,559 @@
+// Package ragkb implements the rag.Backend for the BytePlus/Volcengine
+// knowledge base (ragKB): search_knowledge retrieval plus document and
+// collection management.
+package ragkb
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/gin-gonic/gin"
+
+	"rag-backend/kbauth"
+	"rag-backend/kbhttp"
+	"rag-backend/rag"
+)
+
+// Backend searches and manages documents in one ragKB collection.
+type Backend struct {
+	config      *Config
+	http        *kbhttp.Client
+	collections *collectionCache
+}
+
+var (
+	_ rag.DocumentAdder   = (*Backend)(nil)
+	_ rag.DocumentLister  = (*Backend)(nil)
+	_ rag.DocumentDeleter = (*Backend)(nil)
+)
+
+type Config struct {
+	Domain         string
+	AccountID      string
+	AccessKey      string
+	SecretKey      string
+	Region         string
+	ProjectName    string
+	CollectionName string
+	// Scheme is https unless set; Port overrides the scheme's default port.
+	Scheme string
+	Port   int
+	// CAFile adds trusted CA certificates; InsecureSkipVerify disables
+	// certificate checks and is for testing only. Either gives the backend
+	// its own HTTP client with HTTPClient's retry settings.
+	CAFile             string
+	InsecureSkipVerify bool
+	// Search defaults, overridable per request
+	SearchLimit         int
+	DenseWeight         float64
+	RerankSwitch        bool
+	RerankOnlyChunk     bool
+	ChunkGroup          bool
+	ChunkDiffusionCount int
+	Rewrite             bool
+	// CollectionCacheTTL is how long a project's collection list is trusted
+	// when validating per-request collections; defaults to five minutes.
+	CollectionCacheTTL time.Duration
+	// HTTPClient is shared with other backends to pool connections; nil
+	// uses a client with kbhttp.DefaultConfig.
+	HTTPClient *kbhttp.Client
+}
+
+// ragKB API paths
+const (
+	searchKnowledgePath = "/api/knowledge/collection/search_knowledge"
+	listCollectionsPath = "/api/knowledge/collection/list"
+)
+
+func New(config *Config) (*Backend, error) {
+	switch config.Scheme {
+	case "":
+		config.Scheme = "https"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q, expected http or https", config.Scheme)
+	}
+
+	httpClient := config.HTTPClient
+	tlsConfig, err := kbhttp.LoadTLSConfig(config.CAFile, config.InsecureSkipVerify)
+	if err != nil {
+		return nil, err
+	}
+	if httpClient == nil || tlsConfig != nil {
+		httpConfig := kbhttp.DefaultConfig()
+		if httpClient != nil {
+			httpConfig = httpClient.Config()
+		}
+		httpConfig.TLSConfig = tlsConfig
+		httpClient = kbhttp.New(httpConfig)
+	}
+
+	return &Backend{
+		config:      config,
+		http:        httpClient,
+		collections: newCollectionCache(config.CollectionCacheTTL),
+	}, nil
+}
+
+// BaseURL is the scheme and host requests are sent to.
+func (r *Backend) BaseURL() string {
+	host := r.config.Domain
+	if r.config.Port != 0 {
+		host = net.JoinHostPort(host, strconv.Itoa(r.config.Port))
+	}
+	return r.config.Scheme + "://" + host
+}
+
+func (r *Backend) Name() string {
+	return "ragkb"
+}
+
+// ParsesDocuments reports that ragKB parses and chunks uploads itself, so
+// files and URLs are passed through as they are.
+func (r *Backend) ParsesDocuments() bool {
+	return true
+}
+
+// ragKB API request/response types
+type SearchKnowledgeRequest struct {
+	Project        string         `json:"project"`
+	Name           string         `json:"name"`
+	Query          string         `json:"query"`
+	Limit          int            `json:"limit"`
+	QueryParam     *QueryParam    `json:"query_param,omitempty"`
+	PreProcessing  PreProcessing  `json:"pre_processing"`
+	DenseWeight    float64        `json:"dense_weight"`
+	PostProcessing PostProcessing `json:"post_processing"`
+}
+
+// QueryParam scopes a search; DocFilter uses the VikingDB filter DSL over
+// document metadata.
+type QueryParam struct {
+	DocFilter map[string]interface{} `json:"doc_filter,omitempty"`
+}
+
+type PreProcessing struct {
+	NeedInstruction  bool          `json:"need_instruction"`
+	Rewrite          bool          `json:"rewrite"`
+	ReturnTokenUsage bool          `json:"return_token_usage"`
+	Messages         []rag.Message `json:"messages"`
+}
+
+type PostProcessing struct {
+	GetAttachmentLink   bool `json:"get_attachment_link"`
+	ChunkGroup          bool `json:"chunk_group"`
+	RerankOnlyChunk     bool `json:"rerank_only_chunk"`
+	RerankSwitch        bool `json:"rerank_switch"`
+	ChunkDiffusionCount int  `json:"chunk_diffusion_count"`
+}
+
+type SearchKnowledgeResponse struct {
+	Code int                 `json:"code"`
+	Data SearchKnowledgeData `json:"data"`
+}
+
+type SearchKnowledgeData struct {
+	ResultList []KnowledgePoint `json:"result_list"`
+}
+
+type KnowledgePoint struct {
+	ID      string `json:"id,omitempty"`
+	PointID string `json:"point_id,omitempty"`
+	// ChunkID numbers the point within its document; nil when ragKB omits it.
+	ChunkID          *int              `json:"chunk_id,omitempty"`
+	Content          string            `json:"content"`
+	OriginalQuestion string            `json:"original_question,omitempty"`
+	ChunkTitle       string            `json:"chunk_title,omitempty"`
+	DocInfo          DocInfo           `json:"doc_info"`
+	ChunkAttachment  []ChunkAttachment `json:"chunk_attachment,omitempty"`
+	TableChunkFields []TableChunkField `json:"table_chunk_fields,omitempty"`
+	Score            float64           `json:"score,omitempty"`
+	// RerankScore is set when rerank_switch is on; the dense and sparse
+	// scores are the hybrid search components Score combines.
+	RerankScore float64 `json:"rerank_score,omitempty"`
+	DenseScore  float64 `json:"dense_score,omitempty"`
+	SparseScore float64 `json:"sparse_score,omitempty"`
+}
+
+type DocInfo struct {
+	DocID   string `json:"doc_id,omitempty"`
+	DocName string `json:"doc_name"`
+	Title   string `json:"title"`
+}
+
+type ChunkAttachment struct {
+	UUID    string `json:"uuid,omitempty"`
+	Caption string `json:"caption,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Link    string `json:"link"`
+}
+
+type TableChunkField struct {
+	FieldName  string `json:"field_name"`
+	FieldValue string `json:"field_value"`
+}
+
+// Collection listing types
+type ListCollectionsResponse struct {
+	Code      int                 `json:"code"`
+	Message   string              `json:"message"`
+	RequestID string              `json:"request_id"`
+	Data      ListCollectionsData `json:"data"`
+}
+
+type ListCollectionsData struct {
+	CollectionList []Collection `json:"collection_list"`
+}
+
+type Collection struct {
+	CollectionName string   `json:"collection_name"`
+	Description    string   `json:"description"`
+	PrimaryKey     string   `json:"primary_key"`
+	Fields         []Field  `json:"fields"`
+	IndexNames     []string `json:"index_names"`
+	IndexNum       int      `json:"index_num"`
+	CreateTime     int64    `json:"create_time"`
+	UpdateTime     int64    `json:"update_time"`
+	UpdatePerson   string   `json:"update_person"`
+}
+
+type Field struct {
+	FieldName  string      `json:"field_name"`
+	FieldType  string      `json:"field_type"`
+	DefaultVal interface{} `json:"default_val,omitempty"`
+	Dim        int         `json:"dim,omitempty"`
+}
+
+// Sign request using AWS Signature Version 4
+func (r *Backend) signRequest(req *http.Request, body []byte) error {
+	signer := kbauth.Signer{
+		AccessKey: r.config.AccessKey,
+		SecretKey: r.config.SecretKey,
+		Region:    r.config.Region,
+		AccountID: r.config.AccountID,
+	}
+	return signer.Sign(req, body)
+}
+
+// post sends a signed POST to the ragKB API through the shared client, which
+// signs every retry afresh.
+func (r *Backend) post(ctx context.Context, path, contentType string, body []byte, opts ...kbhttp.CallOption) (*kbhttp.Response, error) {
+	url := r.BaseURL() + path
+	return r.http.Do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("V-Account-Id", r.config.AccountID)
+
+		if err := r.signRequest(req, body); err != nil {
+			return nil, fmt.Errorf("failed to sign request: %w", err)
+		}
+		return req, nil
+	}, opts...)
+}
+
+// buildSearchRequest fills a search_knowledge payload from the configured
+// defaults and any per-request overrides in opts.
+func (r *Backend) buildSearchRequest(query string, opts *rag.SearchOptions) *SearchKnowledgeRequest {
+	if opts == nil {
+		opts = &rag.SearchOptions{}
+	}
+
+	// Follow-up questions only make sense against the earlier turns, so let
+	// ragKB rewrite the query from the history unless told otherwise.
+	rewrite := r.config.Rewrite
+	if len(opts.Messages) > 0 {
+		rewrite = true
+	}
+
+	messages := make([]rag.Message, 0, len(opts.Messages)+2)
+	messages = append(messages, rag.Message{Role: "system", Content: ""})
+	messages = append(messages, opts.Messages...)
+	messages = append(messages, rag.Message{Role: "user", Content: query})
+
+	var queryParam *QueryParam
+	if dsl := opts.Filter.DSL(); dsl != nil {
+		queryParam = &QueryParam{DocFilter: dsl}
+	}
+
+	return &SearchKnowledgeRequest{
+		QueryParam:  queryParam,
+		Project:     r.config.ProjectName,
+		Name:        r.config.CollectionName,
+		Query:       query,
+		Limit:       valueOr(opts.TopK, r.config.SearchLimit),
+		DenseWeight: valueOr(opts.DenseWeight, r.config.DenseWeight),
+		PreProcessing: PreProcessing{
+			NeedInstruction:  true,
+			Rewrite:          valueOr(opts.Rewrite, rewrite),
+			ReturnTokenUsage: true,
+			Messages:         messages,
+		},
+		PostProcessing: PostProcessing{
+			GetAttachmentLink:   true,
+			ChunkGroup:          valueOr(opts.ChunkGroup, r.config.ChunkGroup),
+			RerankOnlyChunk:     valueOr(opts.RerankOnlyChunk, r.config.RerankOnlyChunk),
+			RerankSwitch:        valueOr(opts.Rerank, r.config.RerankSwitch),
+			ChunkDiffusionCount: valueOr(opts.ChunkDiffusionCount, r.config.ChunkDiffusionCount),
+		},
+	}
+}
+
+func valueOr[T any](override *T, fallback T) T {
+	if override != nil {
+		return *override
+	}
+	return fallback
+}
+
+// Search knowledge using ragKB API. Collections other than the configured
+// one are checked against the project's collection list first; several
+// collections are searched concurrently and their results merged by score.
+func (r *Backend) Search(ctx context.Context, query string, opts *rag.SearchOptions) ([]*schema.Document, error) {
+	if opts == nil {
+		opts = &rag.SearchOptions{}
+	}
+	if opts.Partition != "" {
+		return nil, fmt.Errorf("partition: %w by ragkb, use a filter instead", rag.ErrUnsupportedOption)
+	}
+
+	project := opts.Project
+	if project == "" {
+		project = r.config.ProjectName
+	}
+	collections := opts.CollectionNames()
+	if len(collections) == 0 {
+		collections = []string{r.config.CollectionName}
+	}
+	if err := r.checkCollections(ctx, project, collections); err != nil {
+		return nil, err
+	}
+
+	if len(collections) == 1 {
+		return r.searchCollection(ctx, query, project, collections[0], opts)
+	}
+
+	results := make([][]*schema.Document, len(collections))
+	errs := make([]error, len(collections))
+	var wg sync.WaitGroup
+	for i, collection := range collections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = r.searchCollection(ctx, query, project, collection, opts)
+		}()
+	}
+	wg.Wait()
+
+	var docs []*schema.Document
+	for i, collection := range collections {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("collection %s: %w", collection, errs[i])
+		}
+		docs = append(docs, results[i]...)
+	}
+	sort.SliceStable(docs, func(a, b int) bool {
+		return docs[a].Score() > docs[b].Score()
+	})
+	if limit := valueOr(opts.TopK, r.config.SearchLimit); len(docs) > limit {
+		docs = docs[:limit]
+	}
+	return docs, nil
+}
+
+// searchCollection runs search_knowledge against one collection.
+func (r *Backend) searchCollection(ctx context.Context, query, project, collection string, opts *rag.SearchOptions) ([]*schema.Document, error) {
+	payload := r.buildSearchRequest(query, opts)
+	payload.Project = project
+	payload.Name = collection
+
+	// Log the collection name being used
+	log.Printf("ragKB API Request - Project: %s, Collection: %s, Query: %s", project, collection, query)
+
+	// Marshal request body
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	// Log the full request payload
+	log.Printf("ragKB API Request Payload: %s", string(body))
+
+	// Execute request
+	resp, err := r.post(ctx, searchKnowledgePath, "application/json; charset=utf-8", body)
+	if err != nil {
+		log.Printf("HTTP request failed: %v", err)
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+	respBody := resp.Body
+
+	log.Printf("ragKB API response status: %d", resp.StatusCode)
+	log.Printf("ragKB API response body: %s", string(respBody))
+
+	// Check HTTP status
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("ragKB API returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	// Parse response
+	var searchResp SearchKnowledgeResponse
+	if err := json.Unmarshal(respBody, &searchResp); err != nil {
+		log.Printf("Failed to unmarshal response: %v, body: %s", err, string(respBody))
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if searchResp.Code != 0 {
+		log.Printf("ragKB API error: code %d, response: %+v", searchResp.Code, searchResp)
+		return nil, fmt.Errorf("ragKB API error: code %d", searchResp.Code)
+	}
+
+	// Convert to schema.Document format
+	docs := make([]*schema.Document, len(searchResp.Data.ResultList))
+	for i, point := range searchResp.Data.ResultList {
+		// Create metadata from doc info and other fields
+		metadata := map[string]interface{}{
+			"collection":  collection,
+			"doc_name":    point.DocInfo.DocName,
+			"title":       point.DocInfo.Title,
+			"chunk_title": point.ChunkTitle,
+		}
+
+		if point.DocInfo.DocID != "" {
+			metadata["doc_id"] = point.DocInfo.DocID
+			if point.ChunkID != nil {
+				metadata["chunk_id"] = *point.ChunkID
+			}
+		}
+
+		if point.OriginalQuestion != "" {
+			metadata["original_question"] = point.OriginalQuestion
+		}
+
+		if len(point.ChunkAttachment) > 0 {
+			links := make([]string, 0, len(point.ChunkAttachment))
+			attachments := make([]rag.Attachment, 0, len(point.ChunkAttachment))
+			for _, attachment := range point.ChunkAttachment {
+				links = append(links, attachment.Link)
+				attachments = append(attachments, rag.Attachment{
+					Type:    attachment.Type,
+					Link:    attachment.Link,
+					Caption: attachment.Caption,
+				})
+			}
+			metadata["attachment_links"] = links
+			metadata["attachments"] = attachments
+		}
+
+		if len(point.TableChunkFields) > 0 {
+			fields := make([]rag.TableField, 0, len(point.TableChunkFields))
+			for _, field := range point.TableChunkFields {
+				fields = append(fields, rag.TableField{Name: field.FieldName, Value: field.FieldValue})
+			}
+			metadata["table_chunk_fields"] = fields
+		}
+
+		// Component scores; the overall score goes through WithScore
+		for key, score := range map[string]float64{
+			"rerank_score": point.RerankScore,
+			"dense_score":  point.DenseScore,
+			"sparse_score": point.SparseScore,
+		} {
+			if score != 0 {
+				metadata[key] = score
+			}
+		}
+
+		docs[i] = (&schema.Document{
+			ID:       pointID(&point),
+			Content:  point.Content,
+			MetaData: metadata,
+		}).WithScore(point.Score)
+	}
+
+	log.Printf("ragKB search success, query=%v, found %d docs", query, len(docs))
+	return docs, nil
+}
+
+// pointID identifies a search result by the knowledge base's own point ID so
+// it stays the same across queries. Points without one fall back to their
+// document and chunk, or to a hash of the document and content when ragKB
+// does not number the chunk.
+func pointID(point *KnowledgePoint) string {
+	switch {
+	case point.PointID != "":
+		return point.PointID
+	case point.ID != "":
+		return point.ID
+	case point.DocInfo.DocID != "" && point.ChunkID != nil:
+		return fmt.Sprintf("%s-%d", point.DocInfo.DocID, *point.ChunkID)
+	default:
+		doc := point.DocInfo.DocID
+		if doc == "" {
+			doc = point.DocInfo.DocName
+		}
+		sum := sha256.Sum256([]byte(doc + "\x00" + point.Content))
+		return "ragkb_" + hex.EncodeToString(sum[:8])
+	}
+}
+
+// ListCollections retrieves all collections of project, or of the account's
+// default project when it is empty
+func (r *Backend) ListCollections(ctx context.Context, project string) (*ListCollectionsResponse, error) {
+	var body []byte
+	if project != "" {
+		var err error
+		if body, err = json.Marshal(map[string]string{"project": project}); err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %v", err)
+		}
+	}
+
+	resp, err := r.post(ctx, listCollectionsPath, "application/json", body)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	body = resp.Body
+
+	if resp.StatusCode != http.StatusOK {
+		log.Printf("Failed to list collections: status %d", resp.StatusCode)
+		return nil, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result ListCollectionsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+
+	log.Printf("Successfully listed collections: %d collections found", len(result.Data.CollectionList))
+	return &result, nil
+}
+
+// ListCollectionsHandler handles HTTP requests to list collections
+func (r *Backend) ListCollectionsHandler(c *gin.Context) {
+	collectionsResp, err := r.ListCollections(c.Request.Context(), c.Query("project"))
+	if err != nil {
+		log.Printf("Failed to list collections: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to list collections",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, collectionsResp)
+}
@@ -0,0 +1,51 @@
+package ragkb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPointID(t *testing.T) {
+	zero, three := 0, 3
+	tests := []struct {
+		name  string
+		point KnowledgePoint
+		want  string
+	}{
+		{name: "point id", point: KnowledgePoint{PointID: "p1", ID: "i1", DocInfo: DocInfo{DocID: "doc_1"}}, want: "p1"},
+		{name: "id", point: KnowledgePoint{ID: "i1", DocInfo: DocInfo{DocID: "doc_1"}}, want: "i1"},
+		{name: "doc and chunk", point: KnowledgePoint{ChunkID: &three, DocInfo: DocInfo{DocID: "doc_1"}}, want: "doc_1-3"},
+		{name: "doc and first chunk", point: KnowledgePoint{ChunkID: &zero, DocInfo: DocInfo{DocID: "doc_1"}}, want: "doc_1-0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pointID(&tt.point); got != tt.want {
+				t.Errorf("pointID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPointIDWithoutChunkID(t *testing.T) {
+	points := []KnowledgePoint{
+		{Content: "first", DocInfo: DocInfo{DocID: "doc_1"}},
+		{Content: "second", DocInfo: DocInfo{DocID: "doc_1"}},
+		{Content: "first", DocInfo: DocInfo{DocID: "doc_2"}},
+		{Content: "first", DocInfo: DocInfo{DocName: "a.md"}},
+		{Content: "first", DocInfo: DocInfo{DocName: "b.md"}},
+	}
+	seen := make(map[string]int)
+	for i := range points {
+		id := pointID(&points[i])
+		if !strings.HasPrefix(id, "ragkb_") {
+			t.Errorf("point %d: id %q is not a content hash", i, id)
+		}
+		if j, dup := seen[id]; dup {
+			t.Errorf("points %d and %d share id %q", j, i, id)
+		}
+		seen[id] = i
+		if again := pointID(&points[i]); again != id {
+			t.Errorf("point %d: id changed from %q to %q", i, id, again)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ragkb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"rag-backend/rag"
+)
+
+const (
+	defaultCollectionCacheTTL = 5 * time.Minute
+	// An unknown name refreshes the list at most this often, so a client
+	// retrying a typo does not turn every query into a list call.
+	collectionRefreshInterval = 30 * time.Second
+)
+
+// collectionCache remembers the collection names of each project.
+type collectionCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]collectionEntry
+}
+
+type collectionEntry struct {
+	names   map[string]bool
+	fetched time.Time
+}
+
+func newCollectionCache(ttl time.Duration) *collectionCache {
+	if ttl <= 0 {
+		ttl = defaultCollectionCacheTTL
+	}
+	return &collectionCache{
+		ttl:     ttl,
+		entries: make(map[string]collectionEntry),
+	}
+}
+
+// checkCollections verifies that every name is a collection of project. The
+// configured collection is trusted without a lookup.
+func (r *Backend) checkCollections(ctx context.Context, project string, names []string) error {
+	if project == r.config.ProjectName && len(names) == 1 && names[0] == r.config.CollectionName {
+		return nil
+	}
+
+	cache := r.collections
+	cache.mu.Lock()
+	entry, ok := cache.entries[project]
+	cache.mu.Unlock()
+
+	age := time.Since(entry.fetched)
+	refresh := !ok || age > cache.ttl
+	if !refresh && age > collectionRefreshInterval {
+		for _, name := range names {
+			if !entry.names[name] {
+				refresh = true
+				break
+			}
+		}
+	}
+
+	if refresh {
+		resp, err := r.ListCollections(ctx, project)
+		if err != nil {
+			return fmt.Errorf("failed to list collections: %w", err)
+		}
+		if resp.Code != 0 {
+			return fmt.Errorf("failed to list collections: code %d: %s", resp.Code, resp.Message)
+		}
+
+		entry = collectionEntry{names: make(map[string]bool), fetched: time.Now()}
+		for _, collection := range resp.Data.CollectionList {
+			entry.names[collection.CollectionName] = true
+		}
+		cache.mu.Lock()
+		cache.entries[project] = entry
+		cache.mu.Unlock()
+		log.Printf("Cached %d collections of project %s", len(entry.names), project)
+	}
+
+	for _, name := range names {
+		if !entry.names[name] {
+			return fmt.Errorf("%w %q in project %q", rag.ErrUnknownCollection, name, project)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package ragkb
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"rag-backend/rag"
+)
+
+// ConnectivityReport describes how far a connectivity check got.
+type ConnectivityReport struct {
+	URL string `json:"url"`
+	// TLS is the negotiated TLS version, empty over plain HTTP.
+	TLS             string `json:"tls,omitempty"`
+	Reachable       bool   `json:"reachable"`
+	SigningOK       bool   `json:"signing_ok"`
+	CollectionFound bool   `json:"collection_found"`
+}
+
+func (r ConnectivityReport) String() string {
+	tlsVersion := r.TLS
+	if tlsVersion == "" {
+		tlsVersion = "none"
+	}
+	return fmt.Sprintf("url=%s tls=%s reachable=%t signing_ok=%t collection_found=%t",
+		r.URL, tlsVersion, r.Reachable, r.SigningOK, r.CollectionFound)
+}
+
+// CheckConnectivity lists the collections of the configured project to check
+// that the endpoint is reachable, its certificate is trusted, requests are
+// signed correctly and the configured collection exists. The report is
+// filled in as far as the check got even when it fails.
+func (r *Backend) CheckConnectivity(ctx context.Context) (*ConnectivityReport, error) {
+	report := &ConnectivityReport{URL: r.BaseURL()}
+
+	body, err := json.Marshal(map[string]string{"project": r.config.ProjectName})
+	if err != nil {
+		return report, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	resp, err := r.post(ctx, listCollectionsPath, "application/json", body)
+	if err != nil {
+		var certErr *tls.CertificateVerificationError
+		if errors.As(err, &certErr) {
+			return report, fmt.Errorf("TLS certificate of %s not trusted, set RAGKB_CA_FILE: %w", r.config.Domain, err)
+		}
+		if r.config.Scheme == "https" && strings.Contains(err.Error(), "server gave HTTP response to HTTPS client") {
+			return report, fmt.Errorf("%s does not speak HTTPS, set RAGKB_SCHEME=http: %w", r.config.Domain, err)
+		}
+		return report, fmt.Errorf("%s unreachable: %w", report.URL, err)
+	}
+	report.Reachable = true
+	if resp.TLS != nil {
+		report.TLS = tls.VersionName(resp.TLS.Version)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return report, fmt.Errorf("request signature rejected with status %d, check the account ID, keys and region: %s", resp.StatusCode, resp.Body)
+	default:
+		return report, fmt.Errorf("API request failed with status %d: %s", resp.StatusCode, resp.Body)
+	}
+	report.SigningOK = true
+
+	var result ListCollectionsResponse
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return report, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Code != 0 {
+		return report, fmt.Errorf("failed to list collections: code %d: %s", result.Code, result.Message)
+	}
+	for _, collection := range result.Data.CollectionList {
+		if collection.CollectionName == r.config.CollectionName {
+			report.CollectionFound = true
+			return report, nil
+		}
+	}
+	return report, fmt.Errorf("%w %q in project %q", rag.ErrUnknownCollection, r.config.CollectionName, r.config.ProjectName)
+}
@@ -0,0 +1,124 @@
+package ragkb_test
+
+import (
+	"context"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-backend/kbauth"
+	"rag-backend/kbhttp"
+	"rag-backend/rag"
+	"rag-backend/ragkb"
+	"rag-backend/ragkb/ragkbtest"
+)
+
+var testSigner = &kbauth.Signer{AccessKey: "ak", SecretKey: "sk", Region: "cn-beijing", AccountID: "2100000000"}
+
+// writeCA writes the fake's certificate to a PEM file to use as RAGKB_CA_FILE.
+func writeCA(t *testing.T, srv *ragkbtest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckConnectivity(t *testing.T) {
+	tlsServer := ragkbtest.NewTLSServer(testSigner, "docs")
+	defer tlsServer.Close()
+	httpServer := ragkbtest.NewServer(testSigner, "docs")
+	defer httpServer.Close()
+	caFile := writeCA(t, tlsServer)
+
+	tests := []struct {
+		name      string
+		server    *ragkbtest.Server
+		configure func(config *ragkb.Config)
+		want      ragkb.ConnectivityReport
+		wantErr   string
+	}{
+		{
+			name:      "custom ca",
+			server:    tlsServer,
+			configure: func(config *ragkb.Config) { config.CAFile = caFile },
+			want:      ragkb.ConnectivityReport{TLS: "TLS 1.3", Reachable: true, SigningOK: true, CollectionFound: true},
+		},
+		{
+			name:      "insecure skip verify",
+			server:    tlsServer,
+			configure: func(config *ragkb.Config) { config.InsecureSkipVerify = true },
+			want:      ragkb.ConnectivityReport{TLS: "TLS 1.3", Reachable: true, SigningOK: true, CollectionFound: true},
+		},
+		{
+			name:    "untrusted ca",
+			server:  tlsServer,
+			wantErr: "not trusted, set RAGKB_CA_FILE",
+		},
+		{
+			name:   "signature rejected",
+			server: tlsServer,
+			configure: func(config *ragkb.Config) {
+				config.CAFile = caFile
+				config.SecretKey = "other"
+			},
+			want:    ragkb.ConnectivityReport{TLS: "TLS 1.3", Reachable: true},
+			wantErr: "request signature rejected with status 401",
+		},
+		{
+			name:   "unknown collection",
+			server: tlsServer,
+			configure: func(config *ragkb.Config) {
+				config.CAFile = caFile
+				config.CollectionName = "missing"
+			},
+			want:    ragkb.ConnectivityReport{TLS: "TLS 1.3", Reachable: true, SigningOK: true},
+			wantErr: rag.ErrUnknownCollection.Error(),
+		},
+		{
+			name:      "https to a plain http server",
+			server:    httpServer,
+			configure: func(config *ragkb.Config) { config.Scheme = "https" },
+			wantErr:   "does not speak HTTPS, set RAGKB_SCHEME=http",
+		},
+		{
+			name:   "plain http",
+			server: httpServer,
+			want:   ragkb.ConnectivityReport{Reachable: true, SigningOK: true, CollectionFound: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.server.Config()
+			config.HTTPClient = kbhttp.New(kbhttp.Config{Timeout: time.Second})
+			if tt.configure != nil {
+				tt.configure(config)
+			}
+			backend, err := ragkb.New(config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := backend.CheckConnectivity(context.Background())
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("CheckConnectivity = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("CheckConnectivity = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if tt.name == "unknown collection" && !errors.Is(err, rag.ErrUnknownCollection) {
+				t.Errorf("error = %v, want ErrUnknownCollection", err)
+			}
+			tt.want.URL = config.Scheme + "://" + config.Domain
+			if *report != tt.want {
+				t.Errorf("report = %+v, want %+v", *report, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,560 @@
+package ragkb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rag-backend/kbhttp"
+	"rag-backend/rag"
+)
+
+// ragKB document management endpoints
+const (
+	addDocPath    = "/api/knowledge/doc/add"
+	docInfoPath   = "/api/knowledge/doc/info"
+	listDocsPath  = "/api/knowledge/doc/list"
+	deleteDocPath = "/api/knowledge/doc/delete"
+)
+
+// codeDocNotExist is the envelope code for requests naming an unknown
+// document.
+const codeDocNotExist = 1000007
+
+// errDocNotFound is returned by callKnowledgeAPI when ragKB does not know
+// the requested document.
+var errDocNotFound = errors.New("document not found")
+
+// maxFilterDeletes caps how many documents a single filter-based delete may remove.
+const maxFilterDeletes = 1000
+
+// Document add types accepted by the doc/add API
+const (
+	addTypeURL  = "url"
+	addTypeFile = "file"
+)
+
+// ragKB document processing states as reported in DocStatus.ProcessStatus
+const (
+	processStatusCompleted  = 0
+	processStatusFailed     = 1
+	processStatusQueued     = 2
+	processStatusProcessing = 3
+)
+
+// KnowledgeAPIResponse is the common envelope returned by the ragKB APIs.
+type KnowledgeAPIResponse struct {
+	Code      int             `json:"code"`
+	Message   string          `json:"message"`
+	RequestID string          `json:"request_id"`
+	Data      json.RawMessage `json:"data"`
+}
+
+type AddDocRequest struct {
+	CollectionName string         `json:"collection_name"`
+	Project        string         `json:"project"`
+	AddType        string         `json:"add_type"`
+	DocID          string         `json:"doc_id,omitempty"`
+	DocName        string         `json:"doc_name,omitempty"`
+	DocType        string         `json:"doc_type,omitempty"`
+	URL            string         `json:"url,omitempty"`
+	Meta           []DocMetaField `json:"meta,omitempty"`
+}
+
+type DocMetaField struct {
+	FieldName  string      `json:"field_name"`
+	FieldType  string      `json:"field_type"`
+	FieldValue interface{} `json:"field_value"`
+}
+
+type AddDocData struct {
+	CollectionName string `json:"collection_name"`
+	Project        string `json:"project"`
+	DocID          string `json:"doc_id"`
+}
+
+type DocInfoRequest struct {
+	CollectionName string `json:"collection_name"`
+	Project        string `json:"project"`
+	DocID          string `json:"doc_id"`
+}
+
+type DocumentInfo struct {
+	DocID      string         `json:"doc_id,omitempty"`
+	DocName    string         `json:"doc_name"`
+	Title      string         `json:"title"`
+	DocType    string         `json:"doc_type,omitempty"`
+	AddType    string         `json:"add_type,omitempty"`
+	URL        string         `json:"url,omitempty"`
+	CreateTime int64          `json:"create_time,omitempty"`
+	UpdateTime int64          `json:"update_time,omitempty"`
+	PointNum   int            `json:"point_num,omitempty"`
+	Status     *DocStatus     `json:"status,omitempty"`
+	Meta       []DocMetaField `json:"meta,omitempty"`
+}
+
+type ListDocsRequest struct {
+	CollectionName string `json:"collection_name"`
+	Project        string `json:"project"`
+	Offset         int    `json:"offset"`
+	Limit          int    `json:"limit"`
+	DocType        string `json:"doc_type,omitempty"`
+}
+
+type ListDocsData struct {
+	CollectionName string         `json:"collection_name"`
+	DocList        []DocumentInfo `json:"doc_list"`
+	Count          int            `json:"count"`
+	TotalNum       int            `json:"total_num"`
+}
+
+type DeleteDocRequest struct {
+	CollectionName string `json:"collection_name"`
+	Project        string `json:"project"`
+	DocID          string `json:"doc_id"`
+}
+
+type DocStatus struct {
+	ProcessStatus int    `json:"process_status"`
+	FailedCode    int    `json:"failed_code,omitempty"`
+	FailedMsg     string `json:"failed_msg,omitempty"`
+}
+
+// AddDocument adds a document to the configured collection and reports the
+// doc_id assigned by ragKB together with its current processing status.
+func (r *Backend) AddDocument(ctx context.Context, doc *rag.AddDocumentRequest) (*rag.AddDocumentResult, error) {
+	if doc.URL == "" && len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%w: either url or content is required", rag.ErrInvalidDocument)
+	}
+
+	meta, err := toDocMeta(doc.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		body        []byte
+		contentType string
+	)
+	if doc.URL != "" {
+		payload := AddDocRequest{
+			CollectionName: r.config.CollectionName,
+			Project:        r.config.ProjectName,
+			AddType:        addTypeURL,
+			DocID:          doc.DocID,
+			DocName:        doc.DocName,
+			DocType:        docTypeOf(doc.DocType, doc.DocName, doc.URL),
+			URL:            doc.URL,
+			Meta:           meta,
+		}
+		body, err = json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		contentType = "application/json"
+	} else {
+		body, contentType, err = r.buildUploadBody(doc, meta)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Printf("ragKB doc/add - Project: %s, Collection: %s, DocName: %s", r.config.ProjectName, r.config.CollectionName, doc.DocName)
+
+	// ragKB parses, chunks and embeds the document itself after doc/add
+	rag.ReportProgress(ctx, rag.StageUpsert, 0, 1)
+	var added AddDocData
+	// A retried doc/add could add the document twice
+	if err := r.callKnowledgeAPI(ctx, addDocPath, contentType, body, &added, kbhttp.NonIdempotent()); err != nil {
+		return nil, err
+	}
+	rag.ReportProgress(ctx, rag.StageUpsert, 1, 1)
+
+	docID := added.DocID
+	if docID == "" {
+		docID = doc.DocID
+	}
+	result := &rag.AddDocumentResult{DocID: docID, Status: rag.StatusQueued}
+	if docID == "" {
+		return result, nil
+	}
+
+	// The document is processed asynchronously; report whatever state it is in now.
+	info, err := r.GetDocumentInfo(ctx, docID)
+	if err != nil {
+		log.Printf("Failed to fetch status of doc %s: %v", docID, err)
+		return result, nil
+	}
+	if info.Status != nil {
+		result.Status = processStatusName(info.Status.ProcessStatus)
+		result.Error = info.Status.FailedMsg
+	}
+
+	log.Printf("ragKB doc/add success, doc_id=%s, status=%s", result.DocID, result.Status)
+	return result, nil
+}
+
+// GetDocumentInfo fetches a single document's details from ragKB.
+func (r *Backend) GetDocumentInfo(ctx context.Context, docID string) (*DocumentInfo, error) {
+	body, err := json.Marshal(DocInfoRequest{
+		CollectionName: r.config.CollectionName,
+		Project:        r.config.ProjectName,
+		DocID:          docID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var info DocumentInfo
+	if err := r.callKnowledgeAPI(ctx, docInfoPath, "application/json", body, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// ListDocuments pages through the documents of the configured collection.
+// Status filtering is done locally, so a page may span several upstream requests.
+// TotalNum is the upstream count for the doc type and ignores the status filter.
+func (r *Backend) ListDocuments(ctx context.Context, opts rag.ListDocumentsOptions) (*rag.ListDocumentsResult, error) {
+	result := &rag.ListDocumentsResult{Documents: []rag.DocumentSummary{}}
+	offset := opts.Offset
+
+	for len(result.Documents) < opts.Limit {
+		body, err := json.Marshal(ListDocsRequest{
+			CollectionName: r.config.CollectionName,
+			Project:        r.config.ProjectName,
+			Offset:         offset,
+			Limit:          opts.Limit,
+			DocType:        opts.DocType,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		var page ListDocsData
+		if err := r.callKnowledgeAPI(ctx, listDocsPath, "application/json", body, &page); err != nil {
+			return nil, err
+		}
+		result.TotalNum = page.TotalNum
+
+		consumed := 0
+		for _, info := range page.DocList {
+			if len(result.Documents) == opts.Limit {
+				break
+			}
+			consumed++
+
+			summary := rag.DocumentSummary{
+				DocID:      info.DocID,
+				DocName:    info.DocName,
+				DocType:    info.DocType,
+				Status:     rag.StatusUnknown,
+				CreateTime: info.CreateTime,
+				ChunkCount: info.PointNum,
+			}
+			if info.Status != nil {
+				summary.Status = processStatusName(info.Status.ProcessStatus)
+			}
+			if opts.Status != "" && summary.Status != opts.Status {
+				continue
+			}
+			result.Documents = append(result.Documents, summary)
+		}
+		offset += consumed
+
+		if len(page.DocList) < opts.Limit || offset >= page.TotalNum {
+			break
+		}
+	}
+
+	result.Count = len(result.Documents)
+	result.HasMore = offset < result.TotalNum
+	if result.HasMore {
+		result.NextOffset = offset
+		result.NextCursor = rag.EncodeCursor(offset)
+	}
+
+	log.Printf("ragKB doc/list success, offset=%d, returned %d of %d docs", opts.Offset, result.Count, result.TotalNum)
+	return result, nil
+}
+
+// DeleteDocuments removes documents from the configured collection. In dry-run
+// mode Deleted lists the documents that would be removed.
+func (r *Backend) DeleteDocuments(ctx context.Context, req *rag.DeleteDocumentsRequest) (*rag.DeleteDocumentsResult, error) {
+	if len(req.IDs) == 0 && req.Filter.IsEmpty() {
+		return nil, fmt.Errorf("either ids or filter is required")
+	}
+
+	result := &rag.DeleteDocumentsResult{
+		DryRun:  req.DryRun,
+		Deleted: []string{},
+		Failed:  map[string]string{},
+	}
+
+	targets := make([]string, 0, len(req.IDs))
+	seen := make(map[string]bool)
+	for _, id := range req.IDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if req.DryRun {
+			// Only report documents that actually exist
+			_, err := r.GetDocumentInfo(ctx, id)
+			if errors.Is(err, errDocNotFound) {
+				result.NotFound = append(result.NotFound, id)
+				continue
+			}
+			if err != nil {
+				result.Failed[id] = err.Error()
+				continue
+			}
+		}
+		targets = append(targets, id)
+	}
+
+	if !req.Filter.IsEmpty() {
+		matched, err := r.findDocumentsByMeta(ctx, req.Filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range matched {
+			if !seen[id] {
+				seen[id] = true
+				targets = append(targets, id)
+			}
+		}
+	}
+
+	for _, id := range targets {
+		if !req.DryRun {
+			err := r.deleteDocument(ctx, id)
+			if errors.Is(err, errDocNotFound) {
+				result.NotFound = append(result.NotFound, id)
+				continue
+			}
+			if err != nil {
+				log.Printf("Failed to delete doc %s: %v", id, err)
+				result.Failed[id] = err.Error()
+				continue
+			}
+		}
+		result.Deleted = append(result.Deleted, id)
+	}
+
+	result.Count = len(result.Deleted)
+	log.Printf("ragKB doc/delete finished, dry_run=%v, deleted=%d, failed=%d", req.DryRun, result.Count, len(result.Failed))
+	return result, nil
+}
+
+func (r *Backend) deleteDocument(ctx context.Context, docID string) error {
+	body, err := json.Marshal(DeleteDocRequest{
+		CollectionName: r.config.CollectionName,
+		Project:        r.config.ProjectName,
+		DocID:          docID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	// A retry after a delete that went through would report the document
+	// as not found
+	return r.callKnowledgeAPI(ctx, deleteDocPath, "application/json", body, nil, kbhttp.NonIdempotent())
+}
+
+// findDocumentsByMeta scans the whole collection for documents whose meta
+// fields match filter. ragKB cannot filter doc/list, so the filter is
+// evaluated here the way a search's doc_filter would be.
+func (r *Backend) findDocumentsByMeta(ctx context.Context, filter *rag.Filter) ([]string, error) {
+	const pageSize = 100
+
+	var matched []string
+	for offset := 0; ; offset += pageSize {
+		body, err := json.Marshal(ListDocsRequest{
+			CollectionName: r.config.CollectionName,
+			Project:        r.config.ProjectName,
+			Offset:         offset,
+			Limit:          pageSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		var page ListDocsData
+		if err := r.callKnowledgeAPI(ctx, listDocsPath, "application/json", body, &page); err != nil {
+			return nil, err
+		}
+
+		for _, info := range page.DocList {
+			if filter.Matches(metaValues(info.Meta)) {
+				matched = append(matched, info.DocID)
+				if len(matched) > maxFilterDeletes {
+					return nil, fmt.Errorf("filter matches more than %d documents", maxFilterDeletes)
+				}
+			}
+		}
+
+		if len(page.DocList) < pageSize || offset+pageSize >= page.TotalNum {
+			return matched, nil
+		}
+	}
+}
+
+// metaValues maps a document's meta fields by name.
+func metaValues(meta []DocMetaField) map[string]interface{} {
+	values := make(map[string]interface{}, len(meta))
+	for _, field := range meta {
+		values[field.FieldName] = field.FieldValue
+	}
+	return values
+}
+
+// buildUploadBody encodes doc as a multipart form carrying the file bytes and
+// the same fields a URL add would send as JSON.
+func (r *Backend) buildUploadBody(doc *rag.AddDocumentRequest, meta []DocMetaField) ([]byte, string, error) {
+	docName := doc.DocName
+	if docName == "" {
+		docName = fmt.Sprintf("document_%d.txt", time.Now().Unix())
+	}
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fields := map[string]string{
+		"collection_name": r.config.CollectionName,
+		"project":         r.config.ProjectName,
+		"add_type":        addTypeFile,
+		"doc_name":        docName,
+		"doc_type":        docTypeOf(doc.DocType, docName, ""),
+	}
+	if doc.DocID != "" {
+		fields["doc_id"] = doc.DocID
+	}
+	if len(meta) > 0 {
+		metaJSON, err := json.Marshal(meta)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to marshal meta: %w", err)
+		}
+		fields["meta"] = string(metaJSON)
+	}
+	for name, value := range fields {
+		if err := writer.WriteField(name, value); err != nil {
+			return nil, "", fmt.Errorf("failed to write form field %s: %w", name, err)
+		}
+	}
+
+	part, err := writer.CreateFormFile("file", docName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := part.Write(doc.Content); err != nil {
+		return nil, "", fmt.Errorf("failed to write file content: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	return buf.Bytes(), writer.FormDataContentType(), nil
+}
+
+// callKnowledgeAPI sends a signed POST to a ragKB endpoint and decodes the
+// data field of the response envelope into out.
+func (r *Backend) callKnowledgeAPI(ctx context.Context, path, contentType string, body []byte, out interface{}, opts ...kbhttp.CallOption) error {
+	resp, err := r.post(ctx, path, contentType, body, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to execute request: %w", err)
+	}
+	respBody := resp.Body
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ragKB API %s returned status %d: %s", path, resp.StatusCode, string(respBody))
+	}
+
+	var apiResp KnowledgeAPIResponse
+	if err := json.Unmarshal(respBody, &apiResp); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if apiResp.Code == codeDocNotExist {
+		return fmt.Errorf("ragKB API %s: %w: %s", path, errDocNotFound, apiResp.Message)
+	}
+	if apiResp.Code != 0 {
+		return fmt.Errorf("ragKB API %s error: code %d, message: %s", path, apiResp.Code, apiResp.Message)
+	}
+
+	if out != nil && len(apiResp.Data) > 0 {
+		if err := json.Unmarshal(apiResp.Data, out); err != nil {
+			return fmt.Errorf("failed to unmarshal response data: %w", err)
+		}
+	}
+	return nil
+}
+
+// toDocMeta converts free-form upload metadata into ragKB typed meta fields.
+func toDocMeta(metadata map[string]interface{}) ([]DocMetaField, error) {
+	meta := make([]DocMetaField, 0, len(metadata))
+	for name, value := range metadata {
+		var fieldType string
+		switch v := value.(type) {
+		case string:
+			fieldType = "string"
+		case bool:
+			fieldType = "bool"
+		case float64:
+			if v == float64(int64(v)) {
+				fieldType = "int64"
+			} else {
+				fieldType = "float32"
+			}
+		case int, int64:
+			fieldType = "int64"
+		case []interface{}:
+			fieldType = "list<string>"
+			for _, item := range v {
+				if _, ok := item.(string); !ok {
+					return nil, fmt.Errorf("%w: metadata field %q: only lists of strings are supported", rag.ErrInvalidDocument, name)
+				}
+			}
+		case []string:
+			fieldType = "list<string>"
+		default:
+			return nil, fmt.Errorf("%w: metadata field %q has unsupported type %T", rag.ErrInvalidDocument, name, value)
+		}
+		meta = append(meta, DocMetaField{FieldName: name, FieldType: fieldType, FieldValue: value})
+	}
+	return meta, nil
+}
+
+// docTypeOf returns docType if set, otherwise guesses it from the file name or URL extension.
+func docTypeOf(docType, docName, url string) string {
+	if docType != "" {
+		return docType
+	}
+	for _, name := range []string{docName, url} {
+		if ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(name)), "."); ext != "" {
+			return ext
+		}
+	}
+	return "txt"
+}
+
+func processStatusName(status int) string {
+	switch status {
+	case processStatusCompleted:
+		return rag.StatusCompleted
+	case processStatusFailed:
+		return rag.StatusFailed
+	case processStatusQueued:
+		return rag.StatusQueued
+	case processStatusProcessing:
+		return rag.StatusProcessing
+	default:
+		return rag.StatusUnknown
+	}
+}
@@ -0,0 +1,169 @@
+package ragkb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"rag-backend/kbhttp"
+	"rag-backend/rag"
+)
+
+// fakeDocs serves doc/list and doc/delete for one collection from memory.
+type fakeDocs struct {
+	mu   sync.Mutex
+	docs []DocumentInfo
+}
+
+func (f *fakeDocs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	response := KnowledgeAPIResponse{Message: "success"}
+	switch r.URL.Path {
+	case listDocsPath:
+		var req ListDocsRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		end := min(req.Offset+req.Limit, len(f.docs))
+		page := ListDocsData{DocList: f.docs[min(req.Offset, end):end], TotalNum: len(f.docs)}
+		page.Count = len(page.DocList)
+		response.Data, _ = json.Marshal(page)
+	case deleteDocPath:
+		var req DeleteDocRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		response.Code = codeDocNotExist
+		for i, doc := range f.docs {
+			if doc.DocID == req.DocID {
+				f.docs = append(f.docs[:i], f.docs[i+1:]...)
+				response.Code = 0
+				break
+			}
+		}
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeDocs) ids() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ids := make([]string, len(f.docs))
+	for i, doc := range f.docs {
+		ids[i] = doc.DocID
+	}
+	return ids
+}
+
+// newDocsBackend returns a backend on a fake collection holding, besides
+// the named documents, 150 others so filters page through the list.
+func newDocsBackend(t *testing.T, docs ...DocumentInfo) (*Backend, *fakeDocs) {
+	t.Helper()
+	fake := &fakeDocs{}
+	for i := 0; i < 150; i++ {
+		fake.docs = append(fake.docs, DocumentInfo{DocID: fmt.Sprintf("filler_%03d", i), Meta: []DocMetaField{{FieldName: "source", FieldType: "string", FieldValue: "import"}}})
+	}
+	fake.docs = append(fake.docs, docs...)
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	host, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend, err := New(&Config{
+		Domain:         host.Host,
+		Scheme:         "http",
+		AccessKey:      "ak",
+		SecretKey:      "sk",
+		ProjectName:    "default",
+		CollectionName: "docs",
+		HTTPClient:     kbhttp.New(kbhttp.Config{Timeout: time.Second}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return backend, fake
+}
+
+func TestDeleteDocumentsByFilter(t *testing.T) {
+	docs := []DocumentInfo{
+		{DocID: "faq_2022", Meta: []DocMetaField{
+			{FieldName: "source", FieldType: "string", FieldValue: "website"},
+			{FieldName: "year", FieldType: "int64", FieldValue: 2022},
+			{FieldName: "tags", FieldType: "list<string>", FieldValue: []string{"faq", "policy"}},
+		}},
+		{DocID: "faq_2024", Meta: []DocMetaField{
+			{FieldName: "source", FieldType: "string", FieldValue: "website"},
+			{FieldName: "year", FieldType: "int64", FieldValue: 2024},
+			{FieldName: "tags", FieldType: "list<string>", FieldValue: []string{"faq"}},
+		}},
+		{DocID: "code_1", Meta: []DocMetaField{
+			{FieldName: "code", FieldType: "string", FieldValue: "1"},
+		}},
+	}
+	tests := []struct {
+		name   string
+		filter string
+		dryRun bool
+		want   []string
+	}{
+		{name: "eq", filter: `{"must": {"source": "website"}}`, want: []string{"faq_2022", "faq_2024"}},
+		{name: "eq dry run", filter: `{"must": {"source": "website"}}`, dryRun: true, want: []string{"faq_2022", "faq_2024"}},
+		{name: "in", filter: `{"must": {"year": [2020, 2022]}}`, want: []string{"faq_2022"}},
+		{name: "list field", filter: `{"must": {"tags": "policy"}}`, want: []string{"faq_2022"}},
+		{name: "must_not", filter: `{"must": {"source": "website"}, "must_not": {"tags": "policy"}}`, want: []string{"faq_2024"}},
+		{name: "range", filter: `{"range": {"year": {"gte": 2023}}}`, want: []string{"faq_2024"}},
+		{name: "typed comparison", filter: `{"must": {"code": 1}}`, want: []string{}},
+		{name: "typed comparison of strings", filter: `{"must": {"code": "1"}}`, want: []string{"code_1"}},
+		{name: "number is not a string", filter: `{"must": {"year": "2022"}}`, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, fake := newDocsBackend(t, docs...)
+			var filter *rag.Filter
+			if err := json.Unmarshal([]byte(tt.filter), &filter); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := backend.DeleteDocuments(context.Background(), &rag.DeleteDocumentsRequest{Filter: filter, DryRun: tt.dryRun})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(result.Deleted)
+			if !reflect.DeepEqual(result.Deleted, tt.want) {
+				t.Errorf("Deleted = %v, want %v", result.Deleted, tt.want)
+			}
+			wantLeft := 150 + len(docs) - len(tt.want)
+			if tt.dryRun {
+				wantLeft = 150 + len(docs)
+			}
+			if left := len(fake.ids()); left != wantLeft {
+				t.Errorf("%d documents left, want %d", left, wantLeft)
+			}
+		})
+	}
+}
+
+func TestDeleteDocumentsFilterLimit(t *testing.T) {
+	backend, fake := newDocsBackend(t)
+	meta := fake.docs[0].Meta
+	for i := 0; len(fake.docs) <= maxFilterDeletes; i++ {
+		fake.docs = append(fake.docs, DocumentInfo{DocID: fmt.Sprintf("more_%d", i), Meta: meta})
+	}
+
+	_, err := backend.DeleteDocuments(context.Background(), &rag.DeleteDocumentsRequest{Filter: &rag.Filter{Must: map[string]interface{}{"source": "import"}}})
+	if err == nil {
+		t.Fatal("deleted more than the filter limit")
+	}
+	if left := len(fake.ids()); left != maxFilterDeletes+1 {
+		t.Errorf("%d documents left, want none deleted", left)
+	}
+}
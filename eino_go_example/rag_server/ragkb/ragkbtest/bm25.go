@@ -0,0 +1,159 @@
+package ragkbtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"rag-backend/ragkb"
+)
+
+// BM25 parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// bm25Index ranks chunks against a query with Okapi BM25.
+type bm25Index struct {
+	chunks    []Chunk
+	docIDs    []string
+	chunkIDs  []int
+	termFreqs []map[string]int
+	lengths   []int
+	docFreq   map[string]int
+	avgLength float64
+}
+
+func newBM25Index(chunks []Chunk) *bm25Index {
+	idx := &bm25Index{
+		chunks:    chunks,
+		docIDs:    make([]string, len(chunks)),
+		chunkIDs:  make([]int, len(chunks)),
+		termFreqs: make([]map[string]int, len(chunks)),
+		lengths:   make([]int, len(chunks)),
+		docFreq:   make(map[string]int),
+	}
+
+	total := 0
+	docChunks := make(map[string]int)
+	for i, chunk := range chunks {
+		// Number chunks within their document, like ragKB does
+		docID := chunk.DocID
+		if docID == "" {
+			sum := sha256.Sum256([]byte(chunk.DocName))
+			docID = "doc_" + hex.EncodeToString(sum[:8])
+		}
+		idx.docIDs[i] = docID
+		idx.chunkIDs[i] = docChunks[docID]
+		docChunks[docID]++
+
+		terms := tokenize(chunk.ChunkTitle + " " + chunk.Content)
+		freqs := make(map[string]int)
+		for _, term := range terms {
+			freqs[term]++
+		}
+		for term := range freqs {
+			idx.docFreq[term]++
+		}
+		idx.termFreqs[i] = freqs
+		idx.lengths[i] = len(terms)
+		total += len(terms)
+	}
+	if len(chunks) > 0 {
+		idx.avgLength = float64(total) / float64(len(chunks))
+	}
+	return idx
+}
+
+// search returns up to limit chunks with a positive score, best first. A
+// non-nil filter skips chunks whose metadata does not match it.
+func (idx *bm25Index) search(query string, limit int, filter map[string]interface{}) []ragkb.KnowledgePoint {
+	type scored struct {
+		index int
+		score float64
+	}
+
+	queryTerms := tokenize(query)
+	n := float64(len(idx.chunks))
+	var results []scored
+	for i, freqs := range idx.termFreqs {
+		if filter != nil && !matchFilter(filter, idx.chunks[i].Meta) {
+			continue
+		}
+		score := 0.0
+		for _, term := range queryTerms {
+			tf := float64(freqs[term])
+			if tf == 0 {
+				continue
+			}
+			df := float64(idx.docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := 1 - bm25B + bm25B*float64(idx.lengths[i])/idx.avgLength
+			score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+		if score > 0 {
+			results = append(results, scored{index: i, score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(a, b int) bool {
+		return results[a].score > results[b].score
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	points := make([]ragkb.KnowledgePoint, len(results))
+	for i, result := range results {
+		chunk := idx.chunks[result.index]
+		docID, chunkID := idx.docIDs[result.index], idx.chunkIDs[result.index]
+		points[i] = ragkb.KnowledgePoint{
+			PointID:    fmt.Sprintf("%s-%d", docID, chunkID),
+			ChunkID:    &chunkID,
+			Content:    chunk.Content,
+			ChunkTitle: chunk.ChunkTitle,
+			DocInfo: ragkb.DocInfo{
+				DocID:   docID,
+				DocName: chunk.DocName,
+				Title:   chunk.Title,
+			},
+			Score:       result.score,
+			SparseScore: result.score,
+		}
+	}
+	return points
+}
+
+// tokenize lowercases text and splits it into words. CJK characters are
+// indexed one per token since they are not separated by spaces.
+func tokenize(text string) []string {
+	var (
+		tokens []string
+		word   strings.Builder
+	)
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+			unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,107 @@
+package ragkbtest
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBM25Search(t *testing.T) {
+	idx := newBM25Index([]Chunk{
+		{DocName: "a.md", Content: "apple banana", Meta: map[string]interface{}{"lang": "en", "year": 2023.0}},
+		{DocName: "a.md", Content: "apple apple apple", Meta: map[string]interface{}{"lang": "en", "year": 2024.0}},
+		{DocName: "b.md", Content: "cherry", Meta: map[string]interface{}{"lang": "fr", "year": 2024.0}},
+		{DocName: "c.md", Content: "苹果 香蕉"},
+	})
+
+	tests := []struct {
+		name   string
+		query  string
+		limit  int
+		filter map[string]interface{}
+		want   []string
+	}{
+		{name: "term frequency ranks first", query: "apple", want: []string{"apple apple apple", "apple banana"}},
+		{name: "more query terms rank first", query: "apple banana", want: []string{"apple banana", "apple apple apple"}},
+		{name: "limit", query: "apple", limit: 1, want: []string{"apple apple apple"}},
+		{name: "no match", query: "durian", want: nil},
+		{name: "case insensitive", query: "CHERRY", want: []string{"cherry"}},
+		{name: "cjk characters", query: "香蕉", want: []string{"苹果 香蕉"}},
+		{
+			name:   "filter",
+			query:  "apple",
+			filter: map[string]interface{}{"op": "range", "field": "year", "lt": 2024.0},
+			want:   []string{"apple banana"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			points := idx.search(tt.query, tt.limit, tt.filter)
+			var got []string
+			for i, point := range points {
+				got = append(got, point.Content)
+				if i > 0 && point.Score > points[i-1].Score {
+					t.Errorf("point %d scores above point %d", i, i-1)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("search(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBM25PointIDs(t *testing.T) {
+	idx := newBM25Index([]Chunk{
+		{DocID: "doc_1", DocName: "a.md", Content: "apple"},
+		{DocID: "doc_1", DocName: "a.md", Content: "apple pie"},
+		{DocID: "doc_2", DocName: "b.md", Content: "apple tart"},
+	})
+	ids := make(map[string]int)
+	for _, point := range idx.search("apple", 0, nil) {
+		ids[point.PointID] = *point.ChunkID
+	}
+	want := map[string]int{"doc_1-0": 0, "doc_1-1": 1, "doc_2-0": 0}
+	if !reflect.DeepEqual(ids, want) {
+		t.Errorf("point IDs = %v, want %v", ids, want)
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	meta := map[string]interface{}{"lang": "en", "year": 2024.0}
+	tests := []struct {
+		name string
+		cond map[string]interface{}
+		want bool
+	}{
+		{name: "must", cond: map[string]interface{}{"op": "must", "field": "lang", "conds": []interface{}{"fr", "en"}}, want: true},
+		{name: "must miss", cond: map[string]interface{}{"op": "must", "field": "lang", "conds": []interface{}{"fr"}}, want: false},
+		{name: "must missing field", cond: map[string]interface{}{"op": "must", "field": "tag", "conds": []interface{}{"en"}}, want: false},
+		{name: "must_not", cond: map[string]interface{}{"op": "must_not", "field": "lang", "conds": []interface{}{"fr"}}, want: true},
+		{name: "range", cond: map[string]interface{}{"op": "range", "field": "year", "gte": 2024.0, "lt": 2025.0}, want: true},
+		{name: "range miss", cond: map[string]interface{}{"op": "range", "field": "year", "gt": 2024.0}, want: false},
+		{
+			name: "and",
+			cond: map[string]interface{}{"op": "and", "conds": []interface{}{
+				map[string]interface{}{"op": "must", "field": "lang", "conds": []interface{}{"en"}},
+				map[string]interface{}{"op": "range", "field": "year", "lt": 2024.0},
+			}},
+			want: false,
+		},
+		{
+			name: "or",
+			cond: map[string]interface{}{"op": "or", "conds": []interface{}{
+				map[string]interface{}{"op": "must", "field": "lang", "conds": []interface{}{"fr"}},
+				map[string]interface{}{"op": "range", "field": "year", "lte": 2024.0},
+			}},
+			want: true,
+		},
+		{name: "unknown operator", cond: map[string]interface{}{"op": "near", "field": "lang"}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchFilter(tt.cond, meta); got != tt.want {
+				t.Errorf("matchFilter = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package ragkbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadCorpus reads chunks from path. A .json file must hold an array of
+// Chunk; a directory contributes every .txt and .md file in it, split into
+// one chunk per blank-line separated paragraph.
+func LoadCorpus(path string) ([]Chunk, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat corpus: %w", err)
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read corpus: %w", err)
+		}
+		var chunks []Chunk
+		if err := json.Unmarshal(data, &chunks); err != nil {
+			return nil, fmt.Errorf("failed to parse corpus %s: %w", path, err)
+		}
+		return chunks, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus directory: %w", err)
+	}
+
+	var chunks []Chunk
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".txt" && ext != ".md") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		title := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		for _, paragraph := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n\n") {
+			paragraph = strings.TrimSpace(paragraph)
+			if paragraph == "" {
+				continue
+			}
+			chunks = append(chunks, Chunk{
+				DocName: entry.Name(),
+				Title:   title,
+				Content: paragraph,
+			})
+		}
+	}
+	return chunks, nil
+}
+
+// LoadCollections reads every subdirectory of dir as a corpus of its own,
+// keyed by the subdirectory name. Subdirectories without chunks are skipped.
+func LoadCollections(dir string) (map[string][]Chunk, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus directory: %w", err)
+	}
+
+	collections := make(map[string][]Chunk)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		chunks, err := LoadCorpus(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if len(chunks) > 0 {
+			collections[entry.Name()] = chunks
+		}
+	}
+	return collections, nil
+}
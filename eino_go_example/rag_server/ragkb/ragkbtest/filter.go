@@ -0,0 +1,54 @@
+package ragkbtest
+
+import "fmt"
+
+// matchFilter evaluates a VikingDB filter DSL condition, as sent in
+// query_param.doc_filter, against a chunk's metadata. It supports the must,
+// must_not, range, and and or operators; anything else matches nothing.
+func matchFilter(cond map[string]interface{}, meta map[string]interface{}) bool {
+	op, _ := cond["op"].(string)
+	field, _ := cond["field"].(string)
+	switch op {
+	case "and", "or":
+		conds, _ := cond["conds"].([]interface{})
+		for _, c := range conds {
+			sub, _ := c.(map[string]interface{})
+			matched := matchFilter(sub, meta)
+			if op == "and" && !matched {
+				return false
+			}
+			if op == "or" && matched {
+				return true
+			}
+		}
+		return op == "and"
+	case "must", "must_not":
+		conds, _ := cond["conds"].([]interface{})
+		found := false
+		for _, c := range conds {
+			if value, ok := meta[field]; ok && fmt.Sprint(value) == fmt.Sprint(c) {
+				found = true
+				break
+			}
+		}
+		return found == (op == "must")
+	case "range":
+		value, ok := meta[field].(float64)
+		if !ok {
+			return false
+		}
+		for key, check := range map[string]func(v, bound float64) bool{
+			"gt":  func(v, bound float64) bool { return v > bound },
+			"gte": func(v, bound float64) bool { return v >= bound },
+			"lt":  func(v, bound float64) bool { return v < bound },
+			"lte": func(v, bound float64) bool { return v <= bound },
+		} {
+			if bound, ok := cond[key].(float64); ok && !check(value, bound) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
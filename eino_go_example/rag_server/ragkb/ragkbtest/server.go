@@ -0,0 +1,296 @@
+// Package ragkbtest provides an in-process fake of the ragKB search_knowledge
+// and collection/list APIs, in the spirit of net/http/httptest. It verifies
+// request signatures, ranks a local corpus with BM25 (or serves canned
+// results), and can inject failures, so the ragkb backend and the HTTP
+// handlers can be exercised with no network.
+package ragkbtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rag-backend/kbauth"
+	"rag-backend/ragkb"
+)
+
+// ragKB endpoints served by the fake
+const (
+	SearchKnowledgePath = "/api/knowledge/collection/search_knowledge"
+	ListCollectionsPath = "/api/knowledge/collection/list"
+)
+
+// codeCollectionNotExist is the envelope code for searches of an unknown
+// collection.
+const codeCollectionNotExist = 1000005
+
+// Chunk is one searchable piece of the local corpus.
+// An empty DocID is derived from DocName.
+type Chunk struct {
+	DocID      string `json:"doc_id,omitempty"`
+	DocName    string `json:"doc_name"`
+	Title      string `json:"title,omitempty"`
+	ChunkTitle string `json:"chunk_title,omitempty"`
+	Content    string `json:"content"`
+	// Meta is matched against query_param.doc_filter. Numbers must be
+	// float64 for range conditions, as they are when decoded from JSON.
+	Meta map[string]interface{} `json:"meta,omitempty"`
+}
+
+// Fault makes requests to one path fail or slow down. A zero Status and Code
+// only adds Latency.
+type Fault struct {
+	// Status is the HTTP status to answer with instead of 200.
+	Status int
+	// Code is the non-zero envelope code to answer with.
+	Code    int
+	Message string
+	// Latency delays the response, or until the client gives up.
+	Latency time.Duration
+}
+
+// Server is a fake ragKB. The zero value is not usable; call NewServer.
+type Server struct {
+	*httptest.Server
+	Signer *kbauth.Signer
+
+	mu          sync.Mutex
+	collection  string
+	indexes     map[string]*bm25Index
+	canned      []ragkb.KnowledgePoint
+	faults      map[string]Fault
+	searches    []ragkb.SearchKnowledgeRequest
+	authFailure int
+}
+
+// NewServer starts a fake serving collection, plus any added with
+// SetCollection, and accepting requests signed by signer. Callers must Close
+// it.
+func NewServer(signer *kbauth.Signer, collection string) *Server {
+	s := newServer(signer, collection)
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// NewTLSServer is NewServer over HTTPS, with a certificate for 127.0.0.1
+// signed by the httptest CA; Certificate returns it.
+func NewTLSServer(signer *kbauth.Signer, collection string) *Server {
+	s := newServer(signer, collection)
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func newServer(signer *kbauth.Signer, collection string) *Server {
+	return &Server{
+		Signer:     signer,
+		collection: collection,
+		indexes:    map[string]*bm25Index{collection: newBM25Index(nil)},
+		faults:     make(map[string]Fault),
+	}
+}
+
+// Domain is the host:port to use as the ragKB domain.
+func (s *Server) Domain() string {
+	return strings.TrimPrefix(strings.TrimPrefix(s.URL, "http://"), "https://")
+}
+
+// scheme is the scheme the fake is served over.
+func (s *Server) scheme() string {
+	if strings.HasPrefix(s.URL, "https://") {
+		return "https"
+	}
+	return "http"
+}
+
+// Config returns a ragkb.Config pointing at the fake, with its credentials
+// and the server's usual search defaults.
+func (s *Server) Config() *ragkb.Config {
+	return &ragkb.Config{
+		Domain:         s.Domain(),
+		Scheme:         s.scheme(),
+		AccountID:      s.Signer.AccountID,
+		AccessKey:      s.Signer.AccessKey,
+		SecretKey:      s.Signer.SecretKey,
+		Region:         s.Signer.Region,
+		ProjectName:    "default",
+		CollectionName: s.collection,
+		SearchLimit:    10,
+		DenseWeight:    0.5,
+		ChunkGroup:     true,
+	}
+}
+
+// SetCorpus replaces the chunks of the default collection searched with BM25.
+func (s *Server) SetCorpus(chunks []Chunk) {
+	s.SetCollection(s.collection, chunks)
+}
+
+// SetCollection adds a collection holding chunks, or replaces its chunks.
+func (s *Server) SetCollection(name string, chunks []Chunk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.indexes[name] = newBM25Index(chunks)
+}
+
+// SetCannedResults makes every search return points as-is, ignoring the
+// corpus. Pass nil to go back to BM25 ranking.
+func (s *Server) SetCannedResults(points []ragkb.KnowledgePoint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.canned = points
+}
+
+// SetFault injects a failure into every request for path until cleared.
+func (s *Server) SetFault(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults[path] = fault
+}
+
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = make(map[string]Fault)
+}
+
+// Searches returns the search_knowledge payloads received so far.
+func (s *Server) Searches() []ragkb.SearchKnowledgeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ragkb.SearchKnowledgeRequest(nil), s.searches...)
+}
+
+// AuthFailures counts requests rejected for a bad signature.
+func (s *Server) AuthFailures() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.authFailure
+}
+
+func (s *Server) handle(w http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeEnvelope(w, http.StatusBadRequest, 400, "failed to read body", nil)
+		return
+	}
+
+	if err := s.Signer.Verify(req, body); err != nil {
+		s.mu.Lock()
+		s.authFailure++
+		s.mu.Unlock()
+		log.Printf("ragkbtest: rejected %s: %v", req.URL.Path, err)
+		writeEnvelope(w, http.StatusUnauthorized, 401, "signature verification failed: "+err.Error(), nil)
+		return
+	}
+
+	s.mu.Lock()
+	fault, hasFault := s.faults[req.URL.Path]
+	s.mu.Unlock()
+	if hasFault {
+		if fault.Latency > 0 {
+			select {
+			case <-time.After(fault.Latency):
+			case <-req.Context().Done():
+				return
+			}
+		}
+		if fault.Status != 0 && fault.Status != http.StatusOK {
+			writeEnvelope(w, fault.Status, fault.Code, fault.Message, nil)
+			return
+		}
+		if fault.Code != 0 {
+			writeEnvelope(w, http.StatusOK, fault.Code, fault.Message, nil)
+			return
+		}
+	}
+
+	switch req.URL.Path {
+	case SearchKnowledgePath:
+		s.handleSearch(w, body)
+	case ListCollectionsPath:
+		s.handleListCollections(w)
+	default:
+		writeEnvelope(w, http.StatusNotFound, 404, "unknown path "+req.URL.Path, nil)
+	}
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, body []byte) {
+	var searchReq ragkb.SearchKnowledgeRequest
+	if err := json.Unmarshal(body, &searchReq); err != nil {
+		writeEnvelope(w, http.StatusBadRequest, 400, "invalid request body", nil)
+		return
+	}
+	if searchReq.Query == "" {
+		writeEnvelope(w, http.StatusOK, 400, "query is required", nil)
+		return
+	}
+
+	s.mu.Lock()
+	s.searches = append(s.searches, searchReq)
+	canned := s.canned
+	index, ok := s.indexes[searchReq.Name]
+	s.mu.Unlock()
+	if !ok && canned == nil {
+		writeEnvelope(w, http.StatusOK, codeCollectionNotExist, "collection not exist: "+searchReq.Name, nil)
+		return
+	}
+
+	points := canned
+	if points == nil {
+		var filter map[string]interface{}
+		if searchReq.QueryParam != nil {
+			filter = searchReq.QueryParam.DocFilter
+		}
+		points = index.search(searchReq.Query, searchReq.Limit, filter)
+		if searchReq.PostProcessing.RerankSwitch {
+			// Squash BM25 into (0, 1) to stand in for a reranker score
+			for i := range points {
+				points[i].RerankScore = points[i].Score / (1 + points[i].Score)
+			}
+		}
+	} else if searchReq.Limit > 0 && len(points) > searchReq.Limit {
+		points = points[:searchReq.Limit]
+	}
+
+	writeEnvelope(w, http.StatusOK, 0, "success", ragkb.SearchKnowledgeData{ResultList: points})
+}
+
+func (s *Server) handleListCollections(w http.ResponseWriter) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.indexes))
+	for name := range s.indexes {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	now := time.Now().Unix()
+	collections := make([]ragkb.Collection, len(names))
+	for i, name := range names {
+		collections[i] = ragkb.Collection{
+			CollectionName: name,
+			Description:    "ragkbtest fake collection",
+			CreateTime:     now,
+			UpdateTime:     now,
+		}
+	}
+	writeEnvelope(w, http.StatusOK, 0, "success", ragkb.ListCollectionsData{CollectionList: collections})
+}
+
+func writeEnvelope(w http.ResponseWriter, status, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"code":       code,
+		"message":    message,
+		"request_id": fmt.Sprintf("ragkbtest-%d", time.Now().UnixNano()),
+		"data":       data,
+	})
+}
@@ -0,0 +1,154 @@
+// Package vikingdb implements the rag.Backend for a VikingDB collection,
+// retrieving with the eino volc_vikingdb retriever.
+package vikingdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/cloudwego/eino-ext/components/retriever/volc_vikingdb"
+	"github.com/cloudwego/eino/components/document"
+	"github.com/cloudwego/eino/components/retriever"
+	"github.com/cloudwego/eino/schema"
+	"github.com/volcengine/volc-sdk-golang/service/vikingdb"
+
+	"rag-backend/chunking"
+	"rag-backend/docparse"
+	"rag-backend/rag"
+)
+
+// Backend searches one VikingDB index and manages the records of its collection.
+type Backend struct {
+	retriever  retriever.Retriever
+	service    *vikingdb.VikingDBService
+	collection *vikingdb.Collection
+	index      *vikingdb.Index
+	chunking   chunking.Config
+	splitter   document.Transformer
+	modelName  string
+}
+
+var _ rag.DocumentDeleter = (*Backend)(nil)
+
+type Config struct {
+	Host           string
+	Region         string
+	AK             string
+	SK             string
+	CollectionName string
+	IndexName      string
+	// Partition is the index partition searched unless a request names one;
+	// empty uses the retriever default.
+	Partition      string
+	ModelName      string
+	TopK           int
+	ScoreThreshold float64
+	// Chunking controls how AddDocument splits documents; the zero value
+	// uses the recursive chunker with its defaults.
+	Chunking chunking.Config
+}
+
+func New(ctx context.Context, config *Config) (*Backend, error) {
+	// Configure VikingDB retriever
+	vikingConfig := &volc_vikingdb.RetrieverConfig{
+		Host:              config.Host,
+		Region:            config.Region,
+		AK:                config.AK,
+		SK:                config.SK,
+		Scheme:            "https",
+		ConnectionTimeout: 0,
+		Collection:        config.CollectionName,
+		Index:             config.IndexName,
+		EmbeddingConfig: volc_vikingdb.EmbeddingConfig{
+			UseBuiltin:  true,
+			ModelName:   config.ModelName,
+			UseSparse:   true,
+			DenseWeight: 0.4,
+		},
+		Partition:      config.Partition,
+		TopK:           &config.TopK,
+		ScoreThreshold: &config.ScoreThreshold,
+		FilterDSL:      nil,
+	}
+
+	// Create VikingDB retriever
+	vikingRetriever, err := volc_vikingdb.NewRetriever(ctx, vikingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create VikingDB retriever: %w", err)
+	}
+
+	// Data management handles for the same collection and index
+	vikingService := vikingdb.NewVikingDBService(config.Host, config.Region, config.AK, config.SK, "https")
+	collection, err := vikingService.GetCollection(config.CollectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VikingDB collection: %w", err)
+	}
+	index, err := vikingService.GetIndex(config.CollectionName, config.IndexName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get VikingDB index: %w", err)
+	}
+
+	splitter, err := chunking.New(ctx, config.Chunking)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chunker: %w", err)
+	}
+
+	return &Backend{
+		retriever:  vikingRetriever,
+		service:    vikingService,
+		collection: collection,
+		index:      index,
+		chunking:   config.Chunking,
+		splitter:   splitter,
+		modelName:  config.ModelName,
+	}, nil
+}
+
+func (r *Backend) Name() string {
+	return "vikingdb"
+}
+
+// ChunkingConfig returns the chunking settings AddDocument uses, as
+// configured: unset ones take their defaults only once a request's overrides
+// are merged in, so the overlap default follows a smaller chunk_size.
+func (r *Backend) ChunkingConfig() chunking.Config {
+	return r.chunking
+}
+
+// Search retrieves the chunks closest to query. top_k, filter and partition
+// are overridable per request; the other search options are ragKB specific.
+func (r *Backend) Search(ctx context.Context, query string, opts *rag.SearchOptions) ([]*schema.Document, error) {
+	if opts != nil && (opts.Project != "" || len(opts.CollectionNames()) > 0) {
+		return nil, fmt.Errorf("project and collections: %w by vikingdb", rag.ErrUnsupportedOption)
+	}
+
+	var options []retriever.Option
+	if opts != nil && opts.TopK != nil {
+		options = append(options, retriever.WithTopK(*opts.TopK))
+	}
+	if opts != nil && !opts.Filter.IsEmpty() {
+		options = append(options, retriever.WithDSLInfo(opts.Filter.DSL()))
+	}
+	if opts != nil && opts.Partition != "" {
+		options = append(options, retriever.WithSubIndex(opts.Partition))
+	}
+
+	docs, err := r.retriever.Retrieve(ctx, query, options...)
+	if err != nil {
+		return nil, fmt.Errorf("retrieval failed: %w", err)
+	}
+
+	// Expose the document fields written by AddDocument like ragKB chunks do
+	for _, doc := range docs {
+		fields, _ := doc.MetaData[volc_vikingdb.ExtraKeyVikingDBFields].(map[string]interface{})
+		for _, name := range []string{docIDField, docNameField, docparse.MetaHeading} {
+			if value, ok := fields[name].(string); ok && value != "" {
+				doc.MetaData[name] = value
+			}
+		}
+	}
+
+	log.Printf("VikingDB retrieve success, query=%v, found %d docs", query, len(docs))
+	return docs, nil
+}
@@ -0,0 +1,213 @@
+package vikingdb
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/volcengine/volc-sdk-golang/service/vikingdb"
+
+	"rag-backend/rag"
+)
+
+// maxFilterDeletes caps how many records a single filter-based delete may remove.
+const maxFilterDeletes = 1000
+
+// chunkScanBatch is how many chunk keys are looked up at once when finding
+// the chunks of a document.
+const chunkScanBatch = 100
+
+// DeleteDocuments removes documents from the collection. An ID is a document
+// ID as returned by AddDocument, whose chunks are all removed, or else the
+// primary key of a single record. In dry-run mode Deleted lists what would be
+// removed.
+func (r *Backend) DeleteDocuments(ctx context.Context, req *rag.DeleteDocumentsRequest) (*rag.DeleteDocumentsResult, error) {
+	if len(req.IDs) == 0 && req.Filter.IsEmpty() {
+		return nil, fmt.Errorf("either ids or filter is required")
+	}
+
+	result := &rag.DeleteDocumentsResult{
+		DryRun:  req.DryRun,
+		Deleted: []string{},
+	}
+
+	var keys, records []string
+	seen := make(map[string]bool)
+	for _, id := range req.IDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		chunks, err := r.documentChunks(id, 0)
+		if err != nil {
+			return nil, err
+		}
+		if len(chunks) == 0 {
+			records = append(records, id)
+			continue
+		}
+		for _, key := range chunks {
+			seen[key] = true
+		}
+		keys = append(keys, chunks...)
+		result.Deleted = append(result.Deleted, id)
+	}
+
+	// IDs that name no document may be primary keys
+	if len(records) > 0 {
+		existing, err := r.fetchExistingIDs(records)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range records {
+			if existing[id] {
+				keys = append(keys, id)
+				result.Deleted = append(result.Deleted, id)
+			} else {
+				result.NotFound = append(result.NotFound, id)
+			}
+		}
+	}
+
+	if !req.Filter.IsEmpty() {
+		matched, err := r.findIDsByFilter(req.Filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range matched {
+			if !seen[id] {
+				seen[id] = true
+				keys = append(keys, id)
+				result.Deleted = append(result.Deleted, id)
+			}
+		}
+	}
+
+	if !req.DryRun {
+		if err := r.deleteKeys(keys); err != nil {
+			return nil, err
+		}
+	}
+
+	result.Count = len(result.Deleted)
+	log.Printf("VikingDB delete finished, dry_run=%v, deleted=%d, records=%d", req.DryRun, result.Count, len(keys))
+	return result, nil
+}
+
+// deleteKeys removes the records with the given primary keys.
+func (r *Backend) deleteKeys(ids []string) error {
+	for start := 0; start < len(ids); start += chunkScanBatch {
+		keys, err := r.primaryKeys(ids[start:min(start+chunkScanBatch, len(ids))])
+		if err != nil {
+			return err
+		}
+		if err := r.collection.DeleteData(keys); err != nil {
+			return fmt.Errorf("failed to delete data: %w", err)
+		}
+	}
+	return nil
+}
+
+// documentChunks returns the primary keys of the chunks of docID numbered
+// first and up. Chunks are numbered without gaps, so the scan stops at the
+// first one missing.
+func (r *Backend) documentChunks(docID string, first int) ([]string, error) {
+	var ids []string
+	for start := first; ; start += chunkScanBatch {
+		batch := make([]string, chunkScanBatch)
+		for i := range batch {
+			_, batch[i] = r.chunkKey(docID, start+i)
+		}
+		existing, err := r.fetchExistingIDs(batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range batch {
+			if !existing[id] {
+				return ids, nil
+			}
+			ids = append(ids, id)
+		}
+	}
+}
+
+// fetchExistingIDs reports which of ids are present in the collection. IDs
+// that are not valid primary keys are not.
+func (r *Backend) fetchExistingIDs(ids []string) (map[string]bool, error) {
+	var keys interface{} = ids
+	if r.intPrimaryKey() {
+		// FetchData only accepts []string or []int
+		intKeys := make([]int, 0, len(ids))
+		for _, id := range ids {
+			if key, err := strconv.ParseInt(id, 10, 64); err == nil {
+				intKeys = append(intKeys, int(key))
+			}
+		}
+		if len(intKeys) == 0 {
+			return map[string]bool{}, nil
+		}
+		keys = intKeys
+	}
+
+	datas, err := r.collection.FetchData(keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch data: %w", err)
+	}
+
+	existing := make(map[string]bool, len(datas))
+	for _, data := range datas {
+		if data.Id != nil {
+			existing[fmt.Sprint(data.Id)] = true
+		}
+	}
+	return existing, nil
+}
+
+// findIDsByFilter returns the primary keys of all records matching filter.
+func (r *Backend) findIDsByFilter(filter *rag.Filter) ([]string, error) {
+	options := vikingdb.NewSearchOptions().
+		SetFilter(filter.DSL()).
+		SetLimit(maxFilterDeletes + 1).
+		SetOutputFields([]string{})
+
+	datas, err := r.index.Search(nil, options)
+	if err != nil {
+		return nil, fmt.Errorf("filter search failed: %w", err)
+	}
+	if len(datas) > maxFilterDeletes {
+		return nil, fmt.Errorf("filter matches more than %d records", maxFilterDeletes)
+	}
+
+	ids := make([]string, 0, len(datas))
+	for _, data := range datas {
+		ids = append(ids, fmt.Sprint(data.Id))
+	}
+	return ids, nil
+}
+
+// primaryKeys converts ids to the collection's primary key type.
+func (r *Backend) primaryKeys(ids []string) (interface{}, error) {
+	if !r.intPrimaryKey() {
+		return ids, nil
+	}
+
+	keys := make([]int64, len(ids))
+	for i, id := range ids {
+		key, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid primary key %q: collection uses int64 keys", id)
+		}
+		keys[i] = key
+	}
+	return keys, nil
+}
+
+func (r *Backend) intPrimaryKey() bool {
+	for _, field := range r.collection.Fields {
+		if field.FieldName == r.collection.PrimaryKey {
+			return field.FieldType == vikingdb.Int64
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package vikingdb
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/volcengine/volc-sdk-golang/service/vikingdb"
+
+	"rag-backend/chunking"
+	"rag-backend/rag"
+)
+
+// fakeCollection serves the data APIs of a single VikingDB collection from
+// memory, keyed by the fmt.Sprint of each primary key.
+type fakeCollection struct {
+	primaryKey string
+
+	mu      sync.Mutex
+	records map[string]map[string]interface{}
+}
+
+func (f *fakeCollection) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Fields      []map[string]interface{} `json:"fields"`
+		PrimaryKeys []interface{}            `json:"primary_keys"`
+	}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if r.ContentLength != 0 {
+		if err := decoder.Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	response := map[string]interface{}{"code": 0, "msg": "success"}
+	switch r.URL.Path {
+	case "/api/collection/upsert_data":
+		for _, fields := range body.Fields {
+			f.records[fmt.Sprint(fields[f.primaryKey])] = fields
+		}
+	case "/api/collection/fetch_data":
+		data := []interface{}{}
+		for _, key := range body.PrimaryKeys {
+			if fields, ok := f.records[fmt.Sprint(key)]; ok {
+				data = append(data, fields)
+			}
+		}
+		response["data"] = data
+	case "/api/collection/del_data":
+		for _, key := range body.PrimaryKeys {
+			delete(f.records, fmt.Sprint(key))
+		}
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+func (f *fakeCollection) keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	keys := make([]string, 0, len(f.records))
+	for key := range f.records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// newTestBackend returns a Backend on a fake collection that vectorizes text
+// itself, so ingestion needs no embedding model.
+func newTestBackend(t *testing.T, keyType string) (*Backend, *fakeCollection) {
+	t.Helper()
+	fake := &fakeCollection{primaryKey: "id", records: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	host, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := chunking.Config{ChunkSize: 20, Overlap: new(int)}
+	splitter, err := chunking.New(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Backend{
+		collection: &vikingdb.Collection{
+			CollectionName:  "docs",
+			PrimaryKey:      "id",
+			Fields:          []vikingdb.Field{{FieldName: "id", FieldType: keyType, IsPrimaryKey: true}, {FieldName: contentField, FieldType: vikingdb.Text}},
+			Vectorize:       []*vikingdb.VectorizeTuple{{}},
+			VikingDBService: vikingdb.NewVikingDBService(host.Host, "test", "ak", "sk", "http"),
+		},
+		chunking: config,
+		splitter: splitter,
+	}, fake
+}
+
+// paragraphs returns n paragraphs that each fit a chunk of the test backend.
+func paragraphs(n int) []byte {
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = fmt.Sprintf("paragraph %d", i)
+	}
+	return []byte(strings.Join(parts, "\n\n"))
+}
+
+func TestAddDocumentReplacesChunks(t *testing.T) {
+	for _, keyType := range []string{vikingdb.String, vikingdb.Int64} {
+		t.Run(keyType, func(t *testing.T) {
+			backend, fake := newTestBackend(t, keyType)
+			ctx := context.Background()
+
+			first, err := backend.AddDocument(ctx, &rag.AddDocumentRequest{DocID: "guide", Content: paragraphs(3)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(first.ChunkIDs) != 3 {
+				t.Fatalf("first version has %d chunks, want 3", len(first.ChunkIDs))
+			}
+
+			second, err := backend.AddDocument(ctx, &rag.AddDocumentRequest{DocID: "guide", Content: paragraphs(1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := fake.keys(), second.ChunkIDs; strings.Join(got, ",") != strings.Join(want, ",") {
+				t.Errorf("collection has %v, want only the new chunks %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDeleteDocuments(t *testing.T) {
+	tests := []struct {
+		name         string
+		keyType      string
+		ids          []string
+		dryRun       bool
+		wantDeleted  []string
+		wantNotFound []string
+		wantLeft     int
+	}{
+		{name: "document", keyType: vikingdb.String, ids: []string{"guide"}, wantDeleted: []string{"guide"}, wantLeft: 1},
+		{name: "document dry run", keyType: vikingdb.String, ids: []string{"guide"}, dryRun: true, wantDeleted: []string{"guide"}, wantLeft: 4},
+		{name: "int64 keys", keyType: vikingdb.Int64, ids: []string{"guide", "notes"}, wantDeleted: []string{"guide", "notes"}, wantLeft: 0},
+		{name: "chunk key", keyType: vikingdb.String, ids: []string{"notes-0"}, wantDeleted: []string{"notes-0"}, wantLeft: 3},
+		{name: "missing", keyType: vikingdb.String, ids: []string{"guide", "missing"}, wantDeleted: []string{"guide"}, wantNotFound: []string{"missing"}, wantLeft: 1},
+		{name: "missing dry run", keyType: vikingdb.String, ids: []string{"missing"}, dryRun: true, wantDeleted: []string{}, wantNotFound: []string{"missing"}, wantLeft: 4},
+		{name: "not an int64 key", keyType: vikingdb.Int64, ids: []string{"missing"}, wantDeleted: []string{}, wantNotFound: []string{"missing"}, wantLeft: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, fake := newTestBackend(t, tt.keyType)
+			ctx := context.Background()
+			for docID, n := range map[string]int{"guide": 3, "notes": 1} {
+				if _, err := backend.AddDocument(ctx, &rag.AddDocumentRequest{DocID: docID, Content: paragraphs(n)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result, err := backend.DeleteDocuments(ctx, &rag.DeleteDocumentsRequest{IDs: tt.ids, DryRun: tt.dryRun})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(result.Deleted) != fmt.Sprint(tt.wantDeleted) {
+				t.Errorf("Deleted = %v, want %v", result.Deleted, tt.wantDeleted)
+			}
+			if fmt.Sprint(result.NotFound) != fmt.Sprint(tt.wantNotFound) {
+				t.Errorf("NotFound = %v, want %v", result.NotFound, tt.wantNotFound)
+			}
+			if left := len(fake.keys()); left != tt.wantLeft {
+				t.Errorf("%d records left, want %d", left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestAddDocumentChunkSizeOverride(t *testing.T) {
+	backend, fake := newTestBackend(t, vikingdb.String)
+	// As configured with no chunking settings
+	backend.chunking = chunking.Config{}
+
+	content := []byte(strings.Repeat("Short sentences fill the text. ", 20))
+	result, err := backend.AddDocument(context.Background(), &rag.AddDocumentRequest{DocID: "guide", Content: content, Chunking: chunking.Config{ChunkSize: 50}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.ChunkIDs) < 2 {
+		t.Fatalf("got %d chunks, want several of at most 50 characters", len(result.ChunkIDs))
+	}
+	for _, key := range fake.keys() {
+		if n := len([]rune(fake.records[key][contentField].(string))); n > 50 {
+			t.Errorf("chunk %s has %d characters, more than 50", key, n)
+		}
+	}
+}
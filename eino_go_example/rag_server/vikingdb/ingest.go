@@ -0,0 +1,321 @@
+package vikingdb
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/cloudwego/eino/schema"
+	"github.com/volcengine/volc-sdk-golang/service/vikingdb"
+
+	"rag-backend/chunking"
+	"rag-backend/docparse"
+	"rag-backend/rag"
+)
+
+// ingestBatchSize caps the chunks per embedding and upsert call.
+const ingestBatchSize = 50
+
+// Fields written for every chunk. contentField is the one the eino retriever
+// reads; the others are only written when the collection defines them.
+const (
+	contentField = "content"
+	docIDField   = "doc_id"
+	docNameField = "doc_name"
+	chunkIDField = "chunk_id"
+)
+
+// sectionFields is the section metadata written to collection fields of the
+// same name.
+var sectionFields = []string{docparse.MetaHeading, docparse.MetaPage, docparse.MetaTableColumns, docparse.MetaTableRows}
+
+// EmbeddingV2 request parameters and response keys
+const (
+	embeddingReturnDense  = "return_dense"
+	embeddingReturnSparse = "return_sparse"
+	embeddingDenseResult  = "sentence_dense_embedding"
+	embeddingSparseResult = "sentence_sparse_embedding"
+	embeddingDataTypeText = "text"
+)
+
+var _ rag.DocumentAdder = (*Backend)(nil)
+
+// AddDocument chunks the parsed sections of a document, or its content as
+// plain text, with the collection's chunking settings and any the request
+// overrides, embeds them with the configured model unless the
+// collection vectorizes text itself, and upserts them with the document's
+// metadata. Chunk primary keys are derived from the doc ID and chunk number,
+// so adding a document again overwrites its chunks; chunks beyond the new
+// count are deleted once the new ones are in.
+func (r *Backend) AddDocument(ctx context.Context, doc *rag.AddDocumentRequest) (*rag.AddDocumentResult, error) {
+	sections := doc.Sections
+	if len(sections) == 0 {
+		// Not parsed by the upload handler: take the content as plain text
+		if doc.URL != "" {
+			return nil, fmt.Errorf("%w: vikingdb ingests content, not urls", rag.ErrInvalidDocument)
+		}
+		if len(doc.Content) == 0 {
+			return nil, fmt.Errorf("%w: content is required", rag.ErrInvalidDocument)
+		}
+		if !utf8.Valid(doc.Content) {
+			return nil, fmt.Errorf("%w: vikingdb only ingests UTF-8 text", rag.ErrInvalidDocument)
+		}
+		sections = []*schema.Document{{Content: string(doc.Content)}}
+	}
+
+	fields := make(map[string]vikingdb.Field, len(r.collection.Fields))
+	for _, field := range r.collection.Fields {
+		fields[field.FieldName] = field
+	}
+	if _, ok := fields[contentField]; !ok {
+		return nil, fmt.Errorf("collection %s has no %q field", r.collection.CollectionName, contentField)
+	}
+	meta, err := metadataFields(fields, doc.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	docID := doc.DocID
+	if docID == "" {
+		sum := sha256.Sum256(append([]byte(doc.DocName+"\x00"), doc.Content...))
+		docID = "doc_" + hex.EncodeToString(sum[:8])
+	}
+
+	splitter := r.splitter
+	if doc.Chunking != (chunking.Config{}) {
+		if splitter, err = chunking.New(ctx, r.chunking.Merge(doc.Chunking)); err != nil {
+			return nil, fmt.Errorf("%w: %w", rag.ErrInvalidDocument, err)
+		}
+	}
+	rag.ReportProgress(ctx, rag.StageChunk, 0, len(sections))
+	chunks, err := splitter.Transform(ctx, sections)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split document: %w", err)
+	}
+	rag.ReportProgress(ctx, rag.StageChunk, len(sections), len(sections))
+
+	datas := make([]vikingdb.Data, 0, len(chunks))
+	ids := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		key, id := r.chunkKey(docID, i)
+		values := map[string]interface{}{
+			r.collection.PrimaryKey: key,
+			contentField:            chunk.Content,
+		}
+		for name, value := range meta {
+			values[name] = value
+		}
+		// Where the chunk came from, for collections with fields to store it
+		source := map[string]interface{}{docIDField: docID, docNameField: doc.DocName, chunkIDField: int64(i)}
+		for _, name := range sectionFields {
+			if value, ok := chunk.MetaData[name]; ok {
+				source[name] = value
+			}
+		}
+		for name, value := range source {
+			if field, ok := fields[name]; ok {
+				if values[name], err = fieldValue(field, value); err != nil {
+					return nil, err
+				}
+			}
+		}
+		datas = append(datas, vikingdb.Data{Fields: values})
+		ids = append(ids, id)
+	}
+
+	for start := 0; start < len(datas); start += ingestBatchSize {
+		// The SDK calls cannot be interrupted, so stop between batches
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := min(start+ingestBatchSize, len(datas))
+		batch := datas[start:end]
+		if len(r.collection.Vectorize) == 0 {
+			if err := r.embed(batch, fields); err != nil {
+				return nil, err
+			}
+			rag.ReportProgress(ctx, rag.StageEmbed, end, len(datas))
+		}
+		if err := r.collection.UpsertData(batch); err != nil {
+			return nil, fmt.Errorf("failed to upsert data: %w", err)
+		}
+		rag.ReportProgress(ctx, rag.StageUpsert, end, len(datas))
+	}
+
+	// A shorter version of the document leaves chunks of the previous one
+	stale, err := r.documentChunks(docID, len(chunks))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find previous chunks: %w", err)
+	}
+	if err := r.deleteKeys(stale); err != nil {
+		return nil, fmt.Errorf("failed to delete previous chunks: %w", err)
+	}
+
+	log.Printf("VikingDB ingest success, doc_id=%s, chunks=%d, replaced=%d", docID, len(ids), len(stale))
+	return &rag.AddDocumentResult{DocID: docID, Status: rag.StatusCompleted, ChunkIDs: ids}, nil
+}
+
+// chunkKey returns the primary key of a chunk and its string form. Int64
+// keys are a hash of the string one.
+func (r *Backend) chunkKey(docID string, chunk int) (interface{}, string) {
+	id := docID + "-" + strconv.Itoa(chunk)
+	if !r.intPrimaryKey() {
+		return id, id
+	}
+	sum := sha256.Sum256([]byte(id))
+	key := int64(binary.BigEndian.Uint64(sum[:8]) & math.MaxInt64)
+	return key, strconv.FormatInt(key, 10)
+}
+
+// embed fills the vector fields of batch with embeddings of its content from
+// the configured model, the same one the retriever embeds queries with.
+func (r *Backend) embed(batch []vikingdb.Data, fields map[string]vikingdb.Field) error {
+	var denseField, sparseField *vikingdb.Field
+	for _, field := range fields {
+		switch field.FieldType {
+		case vikingdb.Vector:
+			denseField = &field
+		case vikingdb.Sparse_Vector:
+			sparseField = &field
+		}
+	}
+	if denseField == nil {
+		return fmt.Errorf("collection %s has neither a vector field nor vectorization configured", r.collection.CollectionName)
+	}
+
+	raw := make([]vikingdb.RawData, len(batch))
+	for i, data := range batch {
+		raw[i] = vikingdb.RawData{DataType: embeddingDataTypeText, Text: data.Fields[contentField].(string)}
+	}
+	model := vikingdb.EmbModel{
+		ModelName: r.modelName,
+		Params: map[string]interface{}{
+			embeddingReturnDense:  true,
+			embeddingReturnSparse: sparseField != nil,
+		},
+	}
+	items, err := r.service.EmbeddingV2(model, raw)
+	if err != nil {
+		return fmt.Errorf("failed to embed chunks: %w", err)
+	}
+
+	dense, ok := items[embeddingDenseResult].([]interface{})
+	if !ok || len(dense) != len(batch) {
+		return fmt.Errorf("unexpected embedding response: %d dense vectors for %d chunks", len(dense), len(batch))
+	}
+	var sparse []interface{}
+	if sparseField != nil {
+		if sparse, ok = items[embeddingSparseResult].([]interface{}); !ok || len(sparse) != len(batch) {
+			return fmt.Errorf("unexpected embedding response: %d sparse vectors for %d chunks", len(sparse), len(batch))
+		}
+	}
+
+	for i, data := range batch {
+		vector, ok := dense[i].([]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected embedding response: dense vector %d is %T", i, dense[i])
+		}
+		if denseField.Dim != 0 && int64(len(vector)) != denseField.Dim {
+			return fmt.Errorf("model %s returns %d dimensions, field %s expects %d", r.modelName, len(vector), denseField.FieldName, denseField.Dim)
+		}
+		data.Fields[denseField.FieldName] = vector
+		if sparseField != nil {
+			data.Fields[sparseField.FieldName] = sparse[i]
+		}
+	}
+	return nil
+}
+
+// metadataFields converts metadata to the types of the collection fields
+// named by its keys.
+func metadataFields(fields map[string]vikingdb.Field, metadata map[string]interface{}) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(metadata))
+	for name, value := range metadata {
+		field, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: metadata %q is not a field of the collection", rag.ErrInvalidDocument, name)
+		}
+		switch field.FieldType {
+		case vikingdb.Vector, vikingdb.Sparse_Vector:
+			return nil, fmt.Errorf("%w: metadata %q is a vector field", rag.ErrInvalidDocument, name)
+		}
+		if field.IsPrimaryKey || name == contentField {
+			return nil, fmt.Errorf("%w: metadata %q is set by ingestion", rag.ErrInvalidDocument, name)
+		}
+
+		converted, err := fieldValue(field, value)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = converted
+	}
+	return values, nil
+}
+
+// fieldValue converts a JSON decoded value to the field's type.
+func fieldValue(field vikingdb.Field, value interface{}) (interface{}, error) {
+	invalid := fmt.Errorf("%w: metadata %q must be %s, got %T", rag.ErrInvalidDocument, field.FieldName, field.FieldType, value)
+	switch field.FieldType {
+	case vikingdb.String, vikingdb.Text:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case vikingdb.Int64:
+		switch v := value.(type) {
+		case int64:
+			return v, nil
+		case int:
+			return int64(v), nil
+		case float64:
+			if v == math.Trunc(v) {
+				return int64(v), nil
+			}
+		}
+	case vikingdb.Float32:
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int64:
+			return float64(v), nil
+		case int:
+			return float64(v), nil
+		}
+	case vikingdb.Bool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case vikingdb.ListString, vikingdb.ListInt64:
+		items, ok := value.([]interface{})
+		if list, isStrings := value.([]string); isStrings {
+			items, ok = make([]interface{}, len(list)), true
+			for i, s := range list {
+				items[i] = s
+			}
+		}
+		if !ok {
+			return nil, invalid
+		}
+		element := vikingdb.Field{FieldName: field.FieldName, FieldType: vikingdb.String}
+		if field.FieldType == vikingdb.ListInt64 {
+			element.FieldType = vikingdb.Int64
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			v, err := fieldValue(element, item)
+			if err != nil {
+				return nil, invalid
+			}
+			list[i] = v
+		}
+		return list, nil
+	default:
+		return value, nil
+	}
+	return nil, invalid
+}